@@ -7,6 +7,8 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 func (db *Database) LoadTable(tableName *string) (t *Table, err error) {
@@ -20,39 +22,49 @@ func (db *Database) LoadTable(tableName *string) (t *Table, err error) {
 	return
 }
 
-func (db *Database) All(q Query, docs *[]Doc) (err error) {
+func (db *Database) All(q Query, docs *[]Doc) (next string, err error) {
 	if q.Limit == 0 {
 		q.Limit = 30
 	}
+	if len(q.Sort) == 0 && q.Order.Field != "" {
+		q.Sort = []Order{q.Order}
+	}
 
 	t, err := db.LoadTable(&q.Table)
 	if err != nil {
 		return
 	}
 
-	indexKey := q.Order.Field + "_" + q.Order.Type
-	tableIndex, ok := t.Indexes.Load(indexKey)
-	if !ok {
-		err = errors.New("index with field path and type [" + q.Order.Field + " " + q.Order.Type + "] not exist")
-		return
-	}
-
-	ti := tableIndex.(*[]IndexItem)
-	for _, item := range *ti {
-		doc, _ := t.Docs.Load(item.Id)
-		*docs = append(*docs, doc.(Doc))
-		if len(*docs) == q.Limit {
-			break
+	start := time.Now()
+	var pageIdList []float64
+	if len(q.Sort) == 1 {
+		// one key, the index is already in order
+		pageIdList, next, err = t.pageSorted(q)
+		if err != nil {
+			return
+		}
+	} else {
+		idList := t.allIds(nil)
+		q.Stats.scan(len(idList))
+		q.Stats.useIndex("id_float64")
+		q.Stats.sortIndexes(q.Sort)
+		sortedIdList, err := db.Sort(&q.Table, idList, q.Sort, 0)
+		if err != nil {
+			return "", err
+		}
+		pageIdList, next, err = db.Page(&q.Table, sortedIdList, q.Sort, q.Offset, q.Limit, q.Cursor)
+		if err != nil {
+			return "", err
 		}
 	}
-
-	//table, _ = db.Tables.LoadOrStore(*tableName, &Table{})
-	//t = table.(*Table)
-	//t.Indexes.Range(func(key, tableIndex interface{}) bool {
-	//	fmt.Println(key, len(*tableIndex.(*[]IndexItem)), tableIndex)
-	//	return true
-	//})
-	//fmt.Println()
+	q.Stats.track(phaseOrder, start)
+	start = time.Now()
+	for _, id := range pageIdList {
+		doc, _ := t.Engine.Get(id)
+		*docs = append(*docs, doc)
+	}
+	q.Stats.fetch(len(pageIdList))
+	q.Stats.track(phaseMultiGet, start)
 
 	return
 }
@@ -68,11 +80,11 @@ func (db *Database) Get(tableName *string, doc *Doc) (err error) {
 	if err != nil {
 		return err
 	}
-	docFound, ok := t.Docs.Load(id)
+	docFound, ok := t.Engine.Get(id)
 	if !ok {
 		return errors.New("doc not found")
 	}
-	*doc = docFound.(Doc)
+	*doc = docFound
 
 	return
 }
@@ -85,24 +97,26 @@ func (db *Database) MultiGet(tableName *string, idList []float64, docs *[]Doc) (
 
 	// load id
 	for _, id := range idList {
-		doc, _ := t.Docs.Load(id)
-		*docs = append(*docs, doc.(Doc))
+		doc, _ := t.Engine.Get(id)
+		*docs = append(*docs, doc)
 	}
 
 	return
 }
 
 func (db *Database) Add(tableName *string, doc *Doc) (err error) {
-	table, _ := db.Tables.LoadOrStore(*tableName, &Table{})
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	table, _ := db.Tables.LoadOrStore(*tableName, NewTable())
 	t := table.(*Table)
 	id, err := doc.GetId()
 	if err != nil {
 		indexKey := "id_float64"
-		tableIndex, ok := t.Indexes.Load(indexKey)
+		ti, ok := t.Engine.LoadIndex(indexKey)
 		if !ok {
 			id = 1
 		} else {
-			ti := tableIndex.(*[]IndexItem)
 			if len(*ti) == 0 {
 				id = 1
 			} else {
@@ -115,18 +129,25 @@ func (db *Database) Add(tableName *string, doc *Doc) (err error) {
 			return
 		}
 	}
-	_, ok := t.Docs.Load(id)
+	_, ok := t.Engine.Get(id)
 	if ok {
 		err = errors.New("duplicate doc id found")
 		return
 	}
-	t.Docs.Store(id, *doc)
+	err = db.log(tableName, doc, 1)
+	if err != nil {
+		return
+	}
+	err = t.Engine.Put(id, *doc)
+	if err != nil {
+		return
+	}
 
 	// add doc
 	db.Tables.Store(*tableName, t)
 
 	// add to index
-	db.Bucket.Push(BucketItem{
+	db.pushIndex(BucketItem{
 		Table: *tableName,
 		Doc:   *doc,
 		Type:  1,
@@ -136,6 +157,9 @@ func (db *Database) Add(tableName *string, doc *Doc) (err error) {
 }
 
 func (db *Database) Replace(tableName *string, doc *Doc) (err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	t, err := db.LoadTable(tableName)
 	if err != nil {
 		return
@@ -146,11 +170,18 @@ func (db *Database) Replace(tableName *string, doc *Doc) (err error) {
 	if err != nil {
 		return err
 	}
-	t.Docs.Store(id, *doc)
+	err = db.log(tableName, doc, 0)
+	if err != nil {
+		return
+	}
+	err = t.Engine.Put(id, *doc)
+	if err != nil {
+		return
+	}
 	db.Tables.Store(*tableName, t)
 
 	//update index
-	db.Bucket.Push(BucketItem{
+	db.pushIndex(BucketItem{
 		Table: *tableName,
 		Doc:   *doc,
 		Type:  0,
@@ -160,6 +191,9 @@ func (db *Database) Replace(tableName *string, doc *Doc) (err error) {
 }
 
 func (db *Database) Update(tableName *string, doc *Doc) (err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	t, err := db.LoadTable(tableName)
 	if err != nil {
 		return
@@ -170,21 +204,28 @@ func (db *Database) Update(tableName *string, doc *Doc) (err error) {
 	if err != nil {
 		return err
 	}
-	oldDoc, ok := t.Docs.Load(id)
+	oldDoc, ok := t.Engine.Get(id)
 
 	//replace new doc
 	if !ok {
 		return errors.New("doc not found to update")
 	} else {
-		result := setNotZero(oldDoc.(Doc).Fields, doc.Fields)
+		result := setNotZero(oldDoc.Fields, doc.Fields)
 		//(*doc).Fields = result.(sync.Map)
 		(*doc).Fields = result.(map[string]interface{})
-		t.Docs.Store(id, *doc)
+		err = db.log(tableName, doc, 0)
+		if err != nil {
+			return
+		}
+		err = t.Engine.Put(id, *doc)
+		if err != nil {
+			return
+		}
 		db.Tables.Store(*tableName, t)
 	}
 
 	//update indexes
-	db.Bucket.Push(BucketItem{
+	db.pushIndex(BucketItem{
 		Table: *tableName,
 		Doc:   *doc,
 		Type:  0,
@@ -194,6 +235,9 @@ func (db *Database) Update(tableName *string, doc *Doc) (err error) {
 }
 
 func (db *Database) Delete(tableName *string, doc *Doc) (err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	t, err := db.LoadTable(tableName)
 	if err != nil {
 		return
@@ -206,11 +250,18 @@ func (db *Database) Delete(tableName *string, doc *Doc) (err error) {
 	if err != nil {
 		return err
 	}
-	t.Docs.Delete(id)
+	err = db.log(tableName, doc, -1)
+	if err != nil {
+		return
+	}
+	err = t.Engine.Delete(id)
+	if err != nil {
+		return
+	}
 	db.Tables.Store(*tableName, t)
 
 	//delete from index
-	db.Bucket.Push(BucketItem{
+	db.pushIndex(BucketItem{
 		Table: *tableName,
 		Doc:   *doc,
 		Type:  -1,
@@ -219,6 +270,71 @@ func (db *Database) Delete(tableName *string, doc *Doc) (err error) {
 	return
 }
 
+func (db *Database) log(tableName *string, doc *Doc, itemType int8) (err error) {
+	return db.logItem(BucketItem{
+		Table: *tableName,
+		Doc:   *doc,
+		Type:  itemType,
+	})
+}
+
+func (db *Database) logItem(item BucketItem) (err error) {
+	if db.Wal == nil {
+		return
+	}
+
+	err = db.Wal.Write(item)
+	if err != nil {
+		return
+	}
+	if db.Journal != nil {
+		err = db.Journal.Write(item)
+		if err != nil {
+			return
+		}
+	}
+	if db.Options.durability(item.Table) == SyncEveryWrite {
+		err = db.Sync()
+		if err != nil {
+			return
+		}
+	}
+	if db.checkpointer != nil {
+		db.checkpointer.check(db.Wal.Size())
+	}
+
+	return
+}
+
+// apply replays a logged item directly, indexes are updated synchronously
+func (db *Database) apply(item *BucketItem) (err error) {
+	if item.Type == 2 {
+		return db.setCaseSensitive(item.Table, item.Field, item.CaseSensitive)
+	}
+	table, _ := db.Tables.LoadOrStore(item.Table, NewTable())
+	t := table.(*Table)
+	id, err := item.Doc.GetId()
+	if err != nil {
+		return
+	}
+	if _, ok := t.Engine.Get(id); ok && item.Type == 1 {
+		// already in snapshot
+		item.Type = 0
+	}
+	switch item.Type {
+	case 1, 0:
+		err = t.Engine.Put(id, item.Doc)
+	case -1:
+		err = t.Engine.Delete(id)
+	}
+	if err != nil {
+		return
+	}
+	db.updateIndex(item)
+
+	return
+}
+
 func (db *Database) Where(tableName *string, wheres *[]Where, whereType *string) (res []float64, err error) {
 	t, err := db.LoadTable(tableName)
 	if err != nil {
@@ -227,31 +343,11 @@ func (db *Database) Where(tableName *string, wheres *[]Where, whereType *string)
 
 	var lists [][]float64
 	for _, where := range *wheres {
-		indexKey := where.Field + "_" + reflect.TypeOf(where.Value).String()
-		tableIndex, ok := t.Indexes.Load(indexKey)
-		if !ok {
-			lists = append(lists, []float64{})
-			continue
-		}
-
-		ti := tableIndex.(*[]IndexItem)
-		//TODO all searches are case insensitive fix it!
-		if reflect.TypeOf(where.Value).String() == "string" {
-			where.Value = strings.ToLower(where.Value.(string))
-		}
-		firstIndex := firstOccurrence(*ti, 0, len(*ti)-1, where.Value, len(*ti))
-		lastIndex := lastOccurrence(*ti, 0, len(*ti)-1, where.Value, len(*ti))
-		if firstIndex != -1 {
-			if lastIndex != -1 {
-				var resultRow []float64
-				for ; firstIndex <= lastIndex; firstIndex++ {
-					resultRow = append(resultRow, (*ti)[firstIndex].Id)
-				}
-				lists = append(lists, resultRow)
-			} else {
-				lists = append(lists, []float64{(*ti)[firstIndex].Id})
-			}
+		ids, err := t.whereIds(where, "")
+		if err != nil {
+			return nil, err
 		}
+		lists = append(lists, ids)
 	}
 
 	if *whereType == "or" {
@@ -262,39 +358,28 @@ func (db *Database) Where(tableName *string, wheres *[]Where, whereType *string)
 }
 
 func (db *Database) Order(tableName *string, list []float64, field *string, fieldType *string, limit *int) (sortedList []float64, err error) {
-	listLength := len(list)
-	if listLength <= 1 {
-		return list, nil
-	}
-
-	t, err := db.LoadTable(tableName)
-	if err != nil {
-		return
-	}
+	return db.Sort(tableName, list, []Order{{Field: *field, Type: *fieldType}}, *limit)
+}
 
-	indexKey := *field + "_" + *fieldType
-	tableIndex, ok := t.Indexes.Load(indexKey)
-	if !ok {
-		err = errors.New("index with field path and type [" + *field + " " + *fieldType + "] not exist")
-		return
-	}
+func (db *Database) pushIndex(item BucketItem) {
+	atomic.AddInt64(&db.pending, 1)
+	db.Bucket.Push(item)
+}
 
-	ti := tableIndex.(*[]IndexItem)
-	for _, item := range *ti {
-		if isExist(list, item.Id) {
-			sortedList = append(sortedList, item.Id)
-			lenSorted := len(sortedList)
-			if lenSorted == listLength || lenSorted == *limit {
-				break
-			}
-		}
+// WaitIndexes blocks until every queued index update is applied
+func (db *Database) WaitIndexes() {
+	for atomic.LoadInt64(&db.pending) > 0 {
+		time.Sleep(time.Millisecond)
 	}
-
-	return
 }
 
 func (db *Database) BucketFunc(items []interface{}) {
 	bItem := items[0].(BucketItem)
+	db.updateIndex(&bItem)
+	atomic.AddInt64(&db.pending, -1)
+}
+
+func (db *Database) updateIndex(bItem *BucketItem) {
 	switch bItem.Type {
 	case 1:
 		err := db.addToIndex(&bItem.Table, &bItem.Doc)
@@ -330,7 +415,7 @@ func (db *Database) addToIndex(tableName *string, doc *Doc) (err error) {
 	}
 
 	iMap := make(map[string]interface{})
-	indexMap(doc.Fields, "", &iMap)
+	t.indexMap(doc.Fields, "", &iMap)
 
 	for key, val := range iMap {
 		valType := reflect.TypeOf(val).String()
@@ -342,13 +427,7 @@ func (db *Database) addToIndex(tableName *string, doc *Doc) (err error) {
 			Value: val,
 		}
 		indexKey := key + "_" + reflect.TypeOf(val).String()
-		tableIndex, _ := t.Indexes.LoadOrStore(indexKey, &([]IndexItem{}))
-		ti := tableIndex.(*[]IndexItem)
-		is := insertSorted(
-			ti,
-			&indexItem,
-		)
-		t.Indexes.Store(indexKey, &is)
+		t.Engine.IndexPut(indexKey, indexItem)
 	}
 	db.Tables.Store(*tableName, t)
 
@@ -368,16 +447,7 @@ func (db *Database) removeFromIndex(tableName *string, doc *Doc) (err error) {
 	}
 
 	//remove old doc from all indexes
-	t.Indexes.Range(func(key, tableIndex interface{}) bool {
-		ti := tableIndex.(*[]IndexItem)
-		for i := 0; i < len(*ti); i++ {
-			if (*ti)[i].Id == id {
-				t.Indexes.Store(key, removeFromIndexSlice(*ti, i))
-				break
-			}
-		}
-		return true
-	})
+	t.Engine.IndexRemove(id)
 	db.Tables.Store(*tableName, t)
 
 	return
@@ -399,55 +469,65 @@ func or(lists [][]float64) (res []float64) {
 	return
 }
 
+// and keeps the ids of the first list found in every other list
 func and(lists [][]float64) (res []float64) {
-	if len(lists) == 1 {
-		return lists[0]
+	if len(lists) <= 1 {
+		if len(lists) == 1 {
+			return lists[0]
+		}
+		return
 	}
-	tested := make(map[float64]bool)
-	for ni, ids := range lists {
-		for _, needId := range ids {
-			_, ok := tested[needId]
-			if ok {
-				break
-			}
-			foundInAllRows := true
-			for si, ids := range lists {
-				if si == ni { //if same row continue
-					continue
-				}
-				foundInRow := false
-				for _, id := range ids {
-					if needId == id {
-						foundInRow = true
-						break
-					}
-				}
-				if !foundInRow {
-					foundInAllRows = false
-					break
-				}
-			}
-			if foundInAllRows {
-				res = append(res, needId)
+
+	// start from the smallest list, the result can not be bigger
+	smallest := 0
+	for i := range lists {
+		if len(lists[i]) < len(lists[smallest]) {
+			smallest = i
+		}
+	}
+	found := make(map[float64]bool, len(lists[smallest]))
+	for _, id := range lists[smallest] {
+		found[id] = true
+	}
+	for i, ids := range lists {
+		if i == smallest {
+			continue
+		}
+		inList := make(map[float64]bool, len(found))
+		for _, id := range ids {
+			if found[id] {
+				inList[id] = true
 			}
-			tested[needId] = true
+		}
+		found = inList
+	}
+
+	for _, id := range lists[0] {
+		if found[id] {
+			res = append(res, id)
+			delete(found, id)
 		}
 	}
 
 	return
 }
 
-func isExist(list []float64, item float64) bool {
-	for _, current := range list {
-		if current == item {
-			return true
+func (t *Table) indexMap(field interface{}, path string, iMap *map[string]interface{}) {
+	walkFields(field, path, func(fieldPath string, val interface{}) {
+		if reflect.TypeOf(val).String() == "string" {
+			if val.(string) == "" {
+				return
+			}
+			if !t.isCaseSensitive(fieldPath) {
+				val = strings.ToLower(val.(string))
+			}
 		}
-	}
-
-	return false
+		(*iMap)[fieldPath] = val
+	})
 }
 
-func indexMap(field interface{}, path string, iMap *map[string]interface{}) {
+// walkFields calls fn with the dotted path of every non nil leaf
+func walkFields(field interface{}, path string, fn func(fieldPath string, val interface{})) {
 	if reflect.TypeOf(field).String() == "map[string]interface {}" {
 		for key, val := range field.(map[string]interface{}) {
 			var tempPath string
@@ -460,15 +540,9 @@ func indexMap(field interface{}, path string, iMap *map[string]interface{}) {
 				continue
 			}
 			if reflect.TypeOf(val).String() == "map[string]interface {}" {
-				indexMap(val, tempPath, iMap)
+				walkFields(val, tempPath, fn)
 			} else {
-				if reflect.TypeOf(val).String() == "string" {
-					if val.(string) == "" {
-						continue
-					}
-					val = strings.ToLower(val.(string))
-				}
-				(*iMap)[tempPath] = val
+				fn(tempPath, val)
 			}
 		}
 	}
@@ -572,38 +646,6 @@ func removeFromIndexSlice(slice []IndexItem, index int) *[]IndexItem {
 	return &slice
 }
 
-func firstOccurrence(arr []IndexItem, low int, high int, needle interface{}, n int) int {
-	counter++
-	if high >= low {
-		mid := low + (high-low)/2
-		if (mid == 0 || compareInterface(needle, ">", arr[mid-1].Value)) &&
-			compareInterface(arr[mid].Value, "==", needle) {
-			return mid
-		} else if compareInterface(needle, ">", arr[mid].Value) {
-			return firstOccurrence(arr, mid+1, high, needle, n)
-		} else {
-			return firstOccurrence(arr, low, mid-1, needle, n)
-		}
-	}
-	return -1
-}
-
-func lastOccurrence(arr []IndexItem, low int, high int, needle interface{}, n int) int {
-	counter++
-	if high >= low {
-		mid := low + (high-low)/2
-		if (mid == n-1 || compareInterface(needle, "<", arr[mid+1].Value)) &&
-			compareInterface(arr[mid].Value, "==", needle) {
-			return mid
-		} else if compareInterface(needle, "<", arr[mid].Value) {
-			return lastOccurrence(arr, low, mid-1, needle, n)
-		} else {
-			return lastOccurrence(arr, mid+1, high, needle, n)
-		}
-	}
-	return -1
-}
-
 func compareInterface(first interface{}, operator string, second interface{}) bool {
 	if reflect.TypeOf(first) != reflect.TypeOf(second) {
 		return false
@@ -668,9 +710,13 @@ func compareInterface(first interface{}, operator string, second interface{}) bo
 }
 
 func getVal(input interface{}, path []string) (out interface{}, err error) {
+	pathError := errors.New("field path not found")
+	// a null on the way has no fields
+	if input == nil {
+		return nil, pathError
+	}
 	inputType := reflect.TypeOf(input).String()
 	mapType := "map[string]interface {}"
-	pathError := errors.New("field path not found")
 	if len(path) > 1 {
 		//if input == nil {
 		//	return
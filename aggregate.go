@@ -0,0 +1,138 @@
+package flexdb
+
+import "errors"
+
+type Aggregate struct {
+	Func  string `json:"func"`  // count, sum, avg, min or max
+	Field string `json:"field"` // numeric field path, count without a field counts docs
+	As    string `json:"as"`    // result key, func_field when empty
+}
+
+func (a *Aggregate) Key() string {
+	if a.As != "" {
+		return a.As
+	}
+	if a.Field == "" {
+		return a.Func
+	}
+
+	return a.Func + "_" + a.Field
+}
+
+func (a *Aggregate) Check() (err error) {
+	switch a.Func {
+	case "count":
+	case "sum", "avg", "min", "max":
+		if a.Field == "" {
+			err = errors.New("aggregate field is empty for func: " + a.Func)
+		}
+	default:
+		err = errors.New("aggregate func is unknown: " + a.Func)
+	}
+
+	return
+}
+
+type aggregateState struct {
+	count int
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func (s *aggregateState) add(value float64) {
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	s.count++
+	s.sum += value
+}
+
+func (s *aggregateState) result(fn string) interface{} {
+	switch fn {
+	case "count":
+		return s.count
+	case "sum":
+		return s.sum
+	}
+	if s.count == 0 {
+		return nil
+	}
+	switch fn {
+	case "avg":
+		return s.sum / float64(s.count)
+	case "min":
+		return s.min
+	case "max":
+		return s.max
+	}
+
+	return nil
+}
+
+func (db *Database) AggregateQuery(q Query) (result interface{}, err error) {
+	if len(q.Aggregate) == 0 {
+		return nil, errors.New("aggregate is empty")
+	}
+	for i := range q.Aggregate {
+		err = q.Aggregate[i].Check()
+		if err != nil {
+			return
+		}
+	}
+	t, err := db.LoadTable(&q.Table)
+	if err != nil {
+		return
+	}
+
+	// without filter every doc counts
+	var matched map[float64]bool
+	count := 0
+	if q.HasFilter() {
+		idList, err := db.Filter(&q.Table, q.filter(), q.IndexMode, q.Stats)
+		if err != nil {
+			return nil, err
+		}
+		matched = make(map[float64]bool, len(idList))
+		for _, id := range idList {
+			matched[id] = true
+		}
+		count = len(idList)
+	} else {
+		count = t.count()
+	}
+
+	values := make(map[string]interface{})
+	for _, aggregate := range q.Aggregate {
+		if aggregate.Func == "count" && aggregate.Field == "" {
+			values[aggregate.Key()] = count
+			continue
+		}
+
+		var state aggregateState
+		ti, ok := t.Engine.LoadIndex(aggregate.Field + "_float64")
+		if !ok {
+			values[aggregate.Key()] = state.result(aggregate.Func)
+			continue
+		}
+		index := *ti
+		if matched == nil && len(index) > 0 && (aggregate.Func == "min" || aggregate.Func == "max") {
+			// index is sorted, ends are the answer
+			state.add(index[0].Value.(float64))
+			state.add(index[len(index)-1].Value.(float64))
+		} else {
+			for _, item := range index {
+				if matched == nil || matched[item.Id] {
+					state.add(item.Value.(float64))
+				}
+			}
+		}
+		values[aggregate.Key()] = state.result(aggregate.Func)
+	}
+	result = values
+
+	return
+}
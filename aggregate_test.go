@@ -0,0 +1,104 @@
+package flexdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateQuery(t *testing.T) {
+	db := newTestTable(t, "orders",
+		`{"id":1,"city":"paris","total":10}`,
+		`{"id":2,"city":"rome","total":40}`,
+		`{"id":3,"city":"paris","total":25}`,
+		`{"id":4,"city":"oslo","note":"no total"}`,
+	)
+	all := []Aggregate{
+		{Func: "count"},
+		{Func: "count", Field: "total"},
+		{Func: "sum", Field: "total"},
+		{Func: "avg", Field: "total"},
+		{Func: "min", Field: "total"},
+		{Func: "max", Field: "total", As: "top"},
+	}
+	tests := []struct {
+		name      string
+		where     []Where
+		aggregate []Aggregate
+		want      map[string]interface{}
+	}{
+		{"whole table", nil, all, map[string]interface{}{
+			"count": 4, "count_total": 3, "sum_total": 75.0, "avg_total": 25.0, "min_total": 10.0, "top": 40.0,
+		}},
+		{"filter", []Where{{Field: "city", Value: "paris"}}, all, map[string]interface{}{
+			"count": 2, "count_total": 2, "sum_total": 35.0, "avg_total": 17.5, "min_total": 10.0, "top": 25.0,
+		}},
+		{"filter matching nothing", []Where{{Field: "city", Value: "lima"}}, all, map[string]interface{}{
+			"count": 0, "count_total": 0, "sum_total": 0.0, "avg_total": nil, "min_total": nil, "top": nil,
+		}},
+		{"no float64 index", nil, []Aggregate{
+			{Func: "sum", Field: "note"},
+			{Func: "avg", Field: "note"},
+			{Func: "min", Field: "missing"},
+			{Func: "max", Field: "missing"},
+		}, map[string]interface{}{
+			"sum_note": 0.0, "avg_note": nil, "min_missing": nil, "max_missing": nil,
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := db.Run(&Query{Table: "orders", Type: "aggregate", Where: test.where, Aggregate: test.aggregate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, test.want) {
+				t.Fatalf("result = %v, want %v", result, test.want)
+			}
+		})
+	}
+}
+
+func TestAggregateMinMaxFromIndexEnds(t *testing.T) {
+	db := newTestTable(t, "orders",
+		`{"id":1,"total":10}`,
+		`{"id":2,"total":40}`,
+		`{"id":3,"total":25}`,
+	)
+	table := "orders"
+	tb, err := db.LoadTable(&table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti, _ := tb.Engine.LoadIndex("total_float64")
+	// a middle value out of order is never read without a filter
+	(*ti)[1].Value = 1000.0
+
+	aggregate := []Aggregate{{Func: "min", Field: "total"}, {Func: "max", Field: "total"}, {Func: "sum", Field: "total"}}
+	result, err := db.Run(&Query{Table: table, Type: "aggregate", Aggregate: aggregate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"min_total": 10.0, "max_total": 40.0, "sum_total": 1050.0}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("result = %v, want %v", result, want)
+	}
+}
+
+func TestAggregateInvalid(t *testing.T) {
+	db := newTestTable(t, "orders", `{"id":1,"total":10}`)
+	tests := []struct {
+		name      string
+		aggregate []Aggregate
+	}{
+		{"empty", nil},
+		{"unknown func", []Aggregate{{Func: "median", Field: "total"}}},
+		{"sum without field", []Aggregate{{Func: "sum"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := db.Run(&Query{Table: "orders", Type: "aggregate", Aggregate: test.aggregate})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
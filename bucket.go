@@ -1,7 +1,10 @@
 package flexdb
 
 type BucketItem struct {
-	Table string
-	Doc   Doc
-	Type  int8
+	Table string `json:"table"`
+	Doc   Doc    `json:"doc"`
+	Type  int8   `json:"type"` // 1 add, 0 replace, -1 delete, 2 case sensitivity of Field
+
+	Field         string `json:"field,omitempty"`
+	CaseSensitive bool   `json:"case_sensitive,omitempty"`
 }
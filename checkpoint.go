@@ -0,0 +1,233 @@
+package flexdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type CheckpointConfig struct {
+	Interval   time.Duration // snapshot every interval, zero disables the timer
+	MaxLogSize int64         // snapshot when the log grows past this many bytes, zero disables it
+}
+
+type checkpointer struct {
+	config CheckpointConfig
+	full   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func (c *checkpointer) check(logSize int64) {
+	if c.config.MaxLogSize <= 0 || logSize < c.config.MaxLogSize {
+		return
+	}
+	select {
+	case c.full <- struct{}{}:
+	default:
+	}
+}
+
+// Checkpoint writes a consistent snapshot into the data directory and truncates the log
+func (db *Database) Checkpoint() (err error) {
+	if db.Path == "" || db.Wal == nil {
+		return errors.New("database is not opened from a path")
+	}
+
+	db.checkpointMu.Lock()
+	defer db.checkpointMu.Unlock()
+
+	// block writers so snapshot and log agree
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.checkpoint()
+}
+
+func (db *Database) checkpoint() (err error) {
+	err = db.writeSnapshotFile()
+	if err != nil {
+		return
+	}
+
+	return db.Wal.Truncate()
+}
+
+func (db *Database) writeSnapshotFile() (err error) {
+	return db.saveSnapshot(filepath.Join(db.Path, "snapshot.db"), db.crypter)
+}
+
+// saveSnapshot replaces the file at path with a snapshot sealed with crypter
+func (db *Database) saveSnapshot(path string, crypter *Crypter) (err error) {
+	tempPath := path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return
+	}
+	if crypter == nil {
+		err = db.Snapshot(file)
+	} else {
+		var buf bytes.Buffer
+		var data []byte
+		err = db.Snapshot(&buf)
+		if err == nil {
+			data, err = sealSnapshotData(buf.Bytes(), crypter)
+		}
+		if err == nil {
+			_, err = file.Write(data)
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return
+	}
+
+	return os.Rename(tempPath, path)
+}
+
+func (db *Database) StartCheckpoint(config CheckpointConfig) (err error) {
+	if db.Path == "" || db.Wal == nil {
+		return errors.New("database is not opened from a path")
+	}
+	db.StopCheckpoint()
+
+	c := &checkpointer{
+		config: config,
+		full:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	db.mu.Lock()
+	db.checkpointer = c
+	db.mu.Unlock()
+
+	go func() {
+		defer close(c.done)
+		var tick <-chan time.Time
+		if config.Interval > 0 {
+			ticker := time.NewTicker(config.Interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-tick:
+				if db.Wal.Size() == 0 {
+					continue
+				}
+			case <-c.full:
+			}
+			err := db.Checkpoint()
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
+	}()
+
+	return
+}
+
+func (db *Database) StopCheckpoint() {
+	db.mu.Lock()
+	c := db.checkpointer
+	db.checkpointer = nil
+	db.mu.Unlock()
+	if c == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+}
+
+func loadSnapshotFile(path string, crypter *Crypter, engines map[string]StorageEngine) (db *Database, err error) {
+	_ = os.Remove(path + ".tmp")
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewDb(), nil
+	}
+	if err != nil {
+		return
+	}
+
+	encrypted := bytes.HasPrefix(data, []byte(encryptedMagic))
+	switch {
+	case encrypted && crypter == nil:
+		return nil, errors.New("database is encrypted, key is required")
+	case !encrypted && crypter != nil:
+		return nil, errors.New("database is not encrypted")
+	case encrypted:
+		data, err = crypter.Open(data[len(encryptedMagic):])
+		if err != nil {
+			return
+		}
+	}
+
+	return loadSnapshot(bytes.NewReader(data), engines)
+}
+
+// sealSnapshotData is snapshot data the way it is stored when encrypted with crypter
+func sealSnapshotData(data []byte, crypter *Crypter) (sealed []byte, err error) {
+	sealed, err = crypter.Seal(data)
+	if err != nil {
+		return
+	}
+
+	return append([]byte(encryptedMagic), sealed...), nil
+}
+
+// resealSnapshotFile writes the snapshot file at path sealed with crypter instead of old
+// into path.tmp, key rotation renames it once every file is ready
+func resealSnapshotFile(path string, old *Crypter, crypter *Crypter) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if old != nil {
+		if !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+			return errors.New("database is not encrypted")
+		}
+		data, err = old.Open(data[len(encryptedMagic):])
+		if err != nil {
+			return
+		}
+	}
+	data, err = sealSnapshotData(data, crypter)
+	if err != nil {
+		return
+	}
+
+	return writeFileSync(path+".tmp", data)
+}
+
+func writeFileSync(path string, data []byte) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return
+}
@@ -0,0 +1,88 @@
+package flexdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackgroundCheckpoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CheckpointConfig
+	}{
+		{"interval", CheckpointConfig{Interval: 10 * time.Millisecond}},
+		{"max log size", CheckpointConfig{MaxLogSize: 256}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			options := Options{Checkpoint: test.config}
+			db, err := Open(dir, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			table := "people"
+			for i := 1; i <= 20; i++ {
+				if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": float64(i), "name": "someone"}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the checkpointer folds the log into the snapshot in the background
+			// writes after the last size triggered checkpoint may stay in the log
+			limit := test.config.MaxLogSize
+			if limit == 0 {
+				limit = 1
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for db.Wal.Size() >= limit && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if size := db.Wal.Size(); size >= limit {
+				t.Fatalf("log size = %d, want below %d", size, limit)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "snapshot.db")); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			db, err = Open(dir, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			result, err := db.Run(&Query{Table: table, Type: "count"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != 20 {
+				t.Fatalf("count = %v, want 20 from the snapshot", result)
+			}
+		})
+	}
+}
+
+func TestStopCheckpoint(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	db, err := Open(dir, Options{Checkpoint: CheckpointConfig{MaxLogSize: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.StopCheckpoint()
+
+	table := "people"
+	if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": 1.0}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if db.Wal.Size() == 0 {
+		t.Fatal("log was truncated after the checkpointer stopped")
+	}
+}
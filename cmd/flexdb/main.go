@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/morteza-r/flexdb"
+)
+
+const usage = `usage:
+  flexdb export -dir <data dir> -table <name> [-file <out.ndjson>]
+  flexdb import -dir <data dir> -table <name> [-file <in.ndjson>]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	dir := flags.String("dir", "", "database data directory")
+	table := flags.String("table", "", "table name")
+	file := flags.String("file", "", "ndjson file, stdin or stdout when empty")
+	_ = flags.Parse(os.Args[2:])
+	if *dir == "" || *table == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	db, err := flexdb.Open(*dir, flexdb.Options{})
+	if err != nil {
+		fatal(err)
+	}
+	switch os.Args[1] {
+	case "export":
+		err = export(db, *table, *file)
+	case "import":
+		err = load(db, *table, *file)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		_ = db.Close()
+		os.Exit(2)
+	}
+	closeErr := db.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func export(db *flexdb.Database, table string, path string) (err error) {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return db.ExportTable(table, w)
+}
+
+func load(db *flexdb.Database, table string, path string) (err error) {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	result, err := db.ImportTable(table, r)
+	for _, lineErr := range result.Errors {
+		fmt.Fprintln(os.Stderr, lineErr)
+	}
+	fmt.Fprintf(os.Stderr, "%d docs added, %d errors\n", result.Added, len(result.Errors))
+
+	return
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
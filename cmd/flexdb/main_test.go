@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/morteza-r/flexdb"
+)
+
+func TestExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flexdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "in.ndjson")
+	out := filepath.Join(dir, "out.ndjson")
+	data := `{"id":1,"name":"ann"}` + "\n" + `{"id":1,"name":"bob"}` + "\n" + `{"id":2,"name":"cat"}` + "\n"
+	if err := ioutil.WriteFile(in, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := flexdb.Open(filepath.Join(dir, "data"), flexdb.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := load(db, "people", in); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// export reads what import wrote after a reopen
+	db, err = flexdb.Open(filepath.Join(dir, "data"), flexdb.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := export(db, "people", out); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":1,"name":"ann"}` + "\n" + `{"id":2,"name":"cat"}` + "\n"
+	if string(exported) != want {
+		t.Fatalf("export = %q, want %q", exported, want)
+	}
+
+	if err := load(db, "people", filepath.Join(dir, "missing.ndjson")); err == nil {
+		t.Fatal("import of a missing file should fail")
+	}
+}
@@ -0,0 +1,238 @@
+package flexdb
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var ErrWrongKey = errors.New("encryption key is wrong")
+
+const encryptedMagic = "flexdb-aes-gcm\n"
+
+type Crypter struct {
+	aead cipher.AEAD
+}
+
+// NewCrypter takes a 16, 24 or 32 byte key for AES-128, AES-192 or AES-256
+func NewCrypter(key []byte) (c *Crypter, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
+	c = &Crypter{
+		aead: aead,
+	}
+
+	return
+}
+
+func (c *Crypter) Seal(plain []byte) (sealed []byte, err error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return
+	}
+
+	return c.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (c *Crypter) Open(data []byte) (plain []byte, err error) {
+	nonceSize := c.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, ErrWrongKey
+	}
+	plain, err = c.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+
+	return
+}
+
+// RotateKey re-encrypts the data directory with newKey in the background,
+// the returned channel gets the result once rotation is done
+func (db *Database) RotateKey(newKey []byte) <-chan error {
+	done := make(chan error, 1)
+	crypter, err := NewCrypter(newKey)
+	if err != nil {
+		done <- err
+		return done
+	}
+	if db.Path == "" || db.Wal == nil {
+		done <- errors.New("database is not opened from a path")
+		return done
+	}
+	db.Tables.Range(func(key, value interface{}) bool {
+		err = checkEngine(key.(string), value.(*Table).Engine, crypter)
+		return err == nil
+	})
+	if err != nil {
+		done <- err
+		return done
+	}
+
+	go func() {
+		done <- db.rotateKey(crypter)
+	}()
+
+	return done
+}
+
+// rotationFiles are rewritten with the new key next to the snapshot,
+// their .tmp files take over once snapshot.db.tmp is renamed
+var rotationFiles = []string{"wal.log", "journal.log", "journal.base"}
+
+// rotateKey writes every file again sealed with crypter, writers are only
+// blocked to encode the snapshot and to copy what they logged meanwhile
+func (db *Database) rotateKey(crypter *Crypter) (err error) {
+	db.checkpointMu.Lock()
+	defer db.checkpointMu.Unlock()
+
+	snapshotTemp := filepath.Join(db.Path, "snapshot.db.tmp")
+	committed := false
+	defer func() {
+		// after the snapshot is renamed Open finishes the rotation
+		if err == nil || committed {
+			return
+		}
+		_ = os.Remove(snapshotTemp)
+		for _, name := range rotationFiles {
+			_ = os.Remove(filepath.Join(db.Path, name) + ".tmp")
+		}
+	}()
+
+	// lines after these offsets are not in the snapshot yet
+	var buf bytes.Buffer
+	var journalOffset int64
+	db.mu.Lock()
+	oldCrypter := db.crypter
+	err = db.Snapshot(&buf)
+	walOffset := db.Wal.Size()
+	if db.Journal != nil {
+		journalOffset = db.Journal.wal.Size()
+	}
+	db.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	// snapshot.db.tmp is created first, Open finishes the rotation once it is renamed
+	data, err := sealSnapshotData(buf.Bytes(), crypter)
+	if err != nil {
+		return
+	}
+	err = writeFileSync(snapshotTemp, data)
+	if err != nil {
+		return
+	}
+	journalPath := filepath.Join(db.Path, "journal.log")
+	var journalTemp *Wal
+	if db.Journal != nil {
+		err = resealSnapshotFile(filepath.Join(db.Path, "journal.base"), oldCrypter, crypter)
+		if err != nil {
+			return
+		}
+		journalTemp, err = openRotationLog(journalPath+".tmp", crypter)
+		if err != nil {
+			return
+		}
+		defer journalTemp.Close()
+		err = copyRecords(journalPath, 0, journalOffset, oldCrypter, journalTemp)
+		if err != nil {
+			return
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	walPath := filepath.Join(db.Path, "wal.log")
+	walTemp, err := openRotationLog(walPath+".tmp", crypter)
+	if err != nil {
+		return
+	}
+	defer walTemp.Close()
+	err = copyRecords(walPath, walOffset, -1, oldCrypter, walTemp)
+	if err == nil {
+		err = walTemp.Sync()
+	}
+	if err == nil && journalTemp != nil {
+		err = copyRecords(journalPath, journalOffset, -1, oldCrypter, journalTemp)
+		if err == nil {
+			err = journalTemp.Sync()
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	err = os.Rename(snapshotTemp, filepath.Join(db.Path, "snapshot.db"))
+	if err != nil {
+		return
+	}
+	committed = true
+	db.crypter = crypter
+	err = db.Wal.swap(walPath+".tmp", crypter)
+	if err != nil || db.Journal == nil {
+		return
+	}
+	err = db.Journal.wal.swap(journalPath+".tmp", crypter)
+	if err != nil {
+		return
+	}
+
+	return renameIfExists(filepath.Join(db.Path, "journal.base.tmp"), filepath.Join(db.Path, "journal.base"))
+}
+
+func openRotationLog(path string, crypter *Crypter) (w *Wal, err error) {
+	w, err = OpenWal(path)
+	if err != nil {
+		return
+	}
+	w.SetCrypter(crypter)
+	err = w.Truncate()
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	return
+}
+
+// finishRotation completes a key rotation a crash interrupted, the rotation
+// happened when snapshot.db.tmp was renamed, otherwise its files are dropped
+func finishRotation(path string) (err error) {
+	_, statErr := os.Stat(filepath.Join(path, "snapshot.db.tmp"))
+	rotated := os.IsNotExist(statErr)
+	for _, name := range rotationFiles {
+		tempPath := filepath.Join(path, name) + ".tmp"
+		if !rotated {
+			_ = os.Remove(tempPath)
+			continue
+		}
+		err = renameIfExists(tempPath, filepath.Join(path, name))
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func renameIfExists(from string, to string) (err error) {
+	err = os.Rename(from, to)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return
+}
@@ -0,0 +1,118 @@
+package flexdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestRotateKeyKeepsConcurrentWrites(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	table := "people"
+	db, err := Open(dir, Options{EncryptionKey: oldKey, Journal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 50; i++ {
+		if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": float64(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// writers keep going while the key rotates
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 51; i <= 300; i++ {
+			if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": float64(i)}}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	err = <-db.RotateKey(newKey)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.WaitIndexes()
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir, Options{EncryptionKey: oldKey, Journal: true}); err == nil {
+		t.Fatal("Open with the old key should fail")
+	}
+	db, err = Open(dir, Options{EncryptionKey: newKey, Journal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	result, err := db.Run(&Query{Table: table, Type: "count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 300 {
+		t.Fatalf("count = %v, want 300", result)
+	}
+	restored, err := RestoreJournal(dir, Options{EncryptionKey: newKey}, RestorePoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = restored.Run(&Query{Table: table, Type: "count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 300 {
+		t.Fatalf("restored count = %v, want 300", result)
+	}
+}
+
+func TestFinishRotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot bool // snapshot.db.tmp was not renamed yet
+		want     string
+	}{
+		{"interrupted", true, "old"},
+		{"committed", false, "new"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			files := map[string]string{"wal.log": "old", "wal.log.tmp": "new", "journal.log": "old", "journal.log.tmp": "new"}
+			if test.snapshot {
+				files["snapshot.db.tmp"] = "new"
+			}
+			for name, data := range files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := finishRotation(dir); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"wal.log", "journal.log"} {
+				data, err := ioutil.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != test.want {
+					t.Fatalf("%s = %q, want %q", name, data, test.want)
+				}
+				if _, err := os.Stat(filepath.Join(dir, name) + ".tmp"); !os.IsNotExist(err) {
+					t.Fatalf("%s.tmp is left behind", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package flexdb
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (db *Database) ImportCSV(tableName string, r io.Reader) (result ImportResult, err error) {
+	reader := csv.NewReader(r)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return
+	}
+	if len(records) == 0 {
+		err = errors.New("csv header is missing")
+		return
+	}
+	header := records[0]
+	rows := records[1:]
+	types := inferColumnTypes(len(header), rows)
+
+	var batch []importLine
+	for i, row := range rows {
+		// header is line 1
+		line := i + 2
+		doc := NewDoc()
+		for column, cell := range row {
+			if column >= len(header) || cell == "" {
+				continue
+			}
+			setPath(doc.Fields, strings.Split(header[column], "."), parseCell(cell, types[column]))
+		}
+		batch = append(batch, importLine{line: line, doc: doc})
+		if len(batch) == ImportBatchSize {
+			db.importBatch(&tableName, batch, &result)
+			batch = batch[:0]
+		}
+	}
+	db.importBatch(&tableName, batch, &result)
+
+	return
+}
+
+func (db *Database) ExportCSV(tableName string, w io.Writer) (err error) {
+	t, err := db.LoadTable(&tableName)
+	if err != nil {
+		return
+	}
+	docs, err := t.sortedDocs()
+	if err != nil {
+		return
+	}
+
+	var rows []map[string]string
+	columns := make(map[string]bool)
+	for _, doc := range docs {
+		row := make(map[string]string)
+		walkFields(doc.Fields, "", func(fieldPath string, val interface{}) {
+			row[fieldPath] = formatCell(val)
+			columns[fieldPath] = true
+		})
+		rows = append(rows, row)
+	}
+
+	// id first, then the rest sorted
+	var header []string
+	for column := range columns {
+		if column != "id" {
+			header = append(header, column)
+		}
+	}
+	sort.Strings(header)
+	header = append([]string{"id"}, header...)
+
+	writer := csv.NewWriter(w)
+	err = writer.Write(header)
+	if err != nil {
+		return
+	}
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, column := range header {
+			record[i] = row[column]
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// inferColumnTypes picks float64, bool or string, the types indexes can compare
+func inferColumnTypes(columns int, rows [][]string) (types []string) {
+	types = make([]string, columns)
+	for column := 0; column < columns; column++ {
+		isFloat, isBool := true, true
+		for _, row := range rows {
+			if column >= len(row) || row[column] == "" {
+				continue
+			}
+			if !isNumber(row[column]) {
+				isFloat = false
+			}
+			if _, err := strconv.ParseBool(row[column]); err != nil {
+				isBool = false
+			}
+		}
+		switch {
+		case isFloat:
+			types[column] = "float64"
+		case isBool:
+			types[column] = "bool"
+		default:
+			types[column] = "string"
+		}
+	}
+
+	return
+}
+
+// isNumber accepts finite decimal numbers, NaN and Inf break json and the float index,
+// zero padded cells like zip codes would lose their padding
+func isNumber(cell string) bool {
+	value, err := strconv.ParseFloat(cell, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return false
+	}
+	digits := strings.TrimLeft(cell, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' && digits[1] != 'e' && digits[1] != 'E' {
+		return false
+	}
+
+	return true
+}
+
+func parseCell(cell string, cellType string) interface{} {
+	switch cellType {
+	case "float64":
+		value, _ := strconv.ParseFloat(cell, 64)
+		return value
+	case "bool":
+		value, _ := strconv.ParseBool(cell)
+		return value
+	}
+
+	return cell
+}
+
+func formatCell(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(val)
+
+	return string(data)
+}
+
+func setPath(fields map[string]interface{}, path []string, val interface{}) {
+	if len(path) == 1 {
+		fields[path[0]] = val
+		return
+	}
+	child, ok := fields[path[0]].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		fields[path[0]] = child
+	}
+	setPath(child, path[1:], val)
+}
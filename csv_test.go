@@ -0,0 +1,104 @@
+package flexdb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInferColumnTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []string
+		want  string
+	}{
+		{"numbers", []string{"1", "-2.5", "1e3", "0", "0.25"}, "float64"},
+		{"empty cells skipped", []string{"1", "", "2"}, "float64"},
+		{"bools", []string{"true", "false"}, "bool"},
+		{"text", []string{"ann", "bob"}, "string"},
+		{"mixed", []string{"1", "ann"}, "string"},
+		{"not a number", []string{"Nan", "NaN"}, "string"},
+		{"infinity", []string{"inf", "-Infinity"}, "string"},
+		{"out of range", []string{"1e400"}, "string"},
+		{"zero padded", []string{"01234", "5"}, "string"},
+		{"hex", []string{"0x10"}, "string"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var rows [][]string
+			for _, cell := range test.cells {
+				rows = append(rows, []string{cell})
+			}
+			types := inferColumnTypes(1, rows)
+			if types[0] != test.want {
+				t.Fatalf("type = %s, want %s", types[0], test.want)
+			}
+		})
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	db := NewDb()
+	table := "people"
+	data := "id,name,zip,age,active,address.city,address.geo.lat\n" +
+		"1,ann,01234,30,true,Paris,48.8\n" +
+		"2,NaN,02000,,false,Rome,\n"
+	result, err := db.ImportCSV(table, strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 2 || len(result.Errors) != 0 {
+		t.Fatalf("result = %+v, want 2 added", result)
+	}
+	want := []map[string]interface{}{
+		{"id": 1.0, "name": "ann", "zip": "01234", "age": 30.0, "active": true,
+			"address": map[string]interface{}{"city": "Paris", "geo": map[string]interface{}{"lat": 48.8}}},
+		{"id": 2.0, "name": "NaN", "zip": "02000", "active": false,
+			"address": map[string]interface{}{"city": "Rome"}},
+	}
+	for i, fields := range want {
+		doc := Doc{Fields: map[string]interface{}{"id": float64(i + 1)}}
+		if err := db.Get(&table, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(doc.Fields, fields) {
+			t.Fatalf("doc %d = %v, want %v", i+1, doc.Fields, fields)
+		}
+	}
+
+	// a text value that looks like NaN must not break snapshots
+	var buf bytes.Buffer
+	if err := db.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":1,"name":"ann","age":30,"address":{"city":"Paris"}}`,
+		`{"id":2,"name":"bob","active":true,"address":{"city":"Rome","zip":"00100"}}`,
+	)
+	var first bytes.Buffer
+	if err := db.ExportCSV("people", &first); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "id,active,address.city,address.zip,age,name\n" +
+		"1,,Paris,,30,ann\n" +
+		"2,true,Rome,00100,,bob\n"
+	if first.String() != wantCSV {
+		t.Fatalf("csv = %q, want %q", first.String(), wantCSV)
+	}
+
+	imported := NewDb()
+	if _, err := imported.ImportCSV("people", bytes.NewReader(first.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := imported.ExportCSV("people", &second); err != nil {
+		t.Fatal(err)
+	}
+	if second.String() != first.String() {
+		t.Fatalf("csv after round trip = %q, want %q", second.String(), first.String())
+	}
+}
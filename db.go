@@ -3,16 +3,29 @@ package flexdb
 import (
 	"errors"
 	"github.com/mdaliyan/bucket"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
-var counter int
-
 //TODO dont index text with long len
 
 type Database struct {
-	Tables sync.Map `json:"tables"` //map[string]Table
-	Bucket bucket.Bucket
+	Tables  sync.Map `json:"tables"` //map[string]Table
+	Bucket  bucket.Bucket
+	Wal     *Wal
+	Journal *Journal
+	Path    string
+	Options Options
+
+	mu           sync.RWMutex
+	checkpointMu sync.Mutex // one checkpoint or key rotation at a time
+	checkpointer *checkpointer
+	syncStop     chan struct{}
+	syncDone     chan struct{}
+	pending      int64
+	crypter      *Crypter
 }
 
 func NewDb() *Database {
@@ -24,14 +37,149 @@ func NewDb() *Database {
 	return &db
 }
 
+func Open(path string, options Options) (db *Database, err error) {
+	err = os.MkdirAll(path, 0755)
+	if err != nil {
+		return
+	}
+	var crypter *Crypter
+	if options.EncryptionKey != nil {
+		crypter, err = NewCrypter(options.EncryptionKey)
+		if err != nil {
+			return
+		}
+	}
+	for tableName, engine := range options.Engines {
+		err = checkEngine(tableName, engine, crypter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = finishRotation(path)
+	if err != nil {
+		return
+	}
+	snapshotPath := filepath.Join(path, "snapshot.db")
+	_, statErr := os.Stat(snapshotPath)
+	hasSnapshot := statErr == nil
+	db, err = loadSnapshotFile(snapshotPath, crypter, options.Engines)
+	if err != nil {
+		return
+	}
+	// tables created after the snapshot, replay finds them in their engine
+	for tableName, engine := range options.Engines {
+		if _, ok := db.Tables.Load(tableName); ok {
+			continue
+		}
+		err = db.CreateTable(tableName, engine)
+		if err != nil {
+			return nil, err
+		}
+	}
+	wal, err := OpenWal(filepath.Join(path, "wal.log"))
+	if err != nil {
+		return nil, err
+	}
+	if crypter != nil && !hasSnapshot && wal.Size() > 0 {
+		_ = wal.Close()
+		return nil, errors.New("database is not encrypted")
+	}
+	wal.SetCrypter(crypter)
+
+	// replay log before accepting new writes
+	err = wal.Replay(db.apply)
+	if err != nil {
+		_ = wal.Close()
+		return nil, err
+	}
+	// configured fields win over changes in snapshot and log
+	for tableName, fields := range options.CaseSensitive {
+		for _, field := range fields {
+			err = db.setCaseSensitive(tableName, field, true)
+			if err != nil {
+				_ = wal.Close()
+				return nil, err
+			}
+		}
+	}
+	if options.Journal {
+		db.Journal, err = OpenJournal(filepath.Join(path, "journal.log"), crypter)
+		if err != nil {
+			_ = wal.Close()
+			return nil, err
+		}
+	}
+	db.Wal = wal
+	db.Path = path
+	db.Options = options
+	db.crypter = crypter
+
+	// docs written before the journal existed, RestoreJournal starts from them
+	if db.Journal != nil && db.Journal.Seq() == 0 {
+		err = db.saveSnapshot(filepath.Join(path, "journal.base"), crypter)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	// an encrypted snapshot is what checks the key on next open
+	if crypter != nil && !hasSnapshot {
+		err = db.Checkpoint()
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	if options.groupCommit() {
+		db.startSyncer()
+	}
+	if options.Checkpoint.Interval > 0 || options.Checkpoint.MaxLogSize > 0 {
+		err = db.StartCheckpoint(options.Checkpoint)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	return
+}
+
+func (db *Database) Close() (err error) {
+	db.StopCheckpoint()
+	db.stopSyncer()
+	if db.Wal == nil {
+		return
+	}
+	err = db.Sync()
+	if err != nil {
+		return
+	}
+	if db.Journal != nil {
+		err = db.Journal.Close()
+		if err != nil {
+			return
+		}
+	}
+
+	return db.Wal.Close()
+}
+
 func (db *Database) Run(q *Query) (result interface{}, err error) {
 	err = q.Check()
 	if err != nil {
 		return
 	}
+	start := time.Now()
+	defer func() {
+		q.Took = time.Since(start)
+	}()
+	q.Next = ""
+	q.Stats = &Stats{IndexesUsed: []string{}}
 	switch q.Type {
 	case "all":
-		result, err = db.AllQuery(*q)
+		result, q.Next, err = db.AllQuery(*q)
 		if err != nil {
 			return
 		}
@@ -41,7 +189,32 @@ func (db *Database) Run(q *Query) (result interface{}, err error) {
 			return
 		}
 	case "mget":
-		result, err = db.MGetQuery(*q)
+		result, q.Next, err = db.MGetQuery(*q)
+		if err != nil {
+			return
+		}
+	case "aggregate":
+		result, err = db.AggregateQuery(*q)
+		if err != nil {
+			return
+		}
+	case "group":
+		result, err = db.GroupQuery(*q)
+		if err != nil {
+			return
+		}
+	case "distinct":
+		result, err = db.DistinctQuery(*q)
+		if err != nil {
+			return
+		}
+	case "explain":
+		result, err = db.ExplainQuery(*q)
+		if err != nil {
+			return
+		}
+	case "count":
+		result, err = db.CountQuery(*q)
 		if err != nil {
 			return
 		}
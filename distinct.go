@@ -0,0 +1,130 @@
+package flexdb
+
+import (
+	"errors"
+	"strings"
+)
+
+type Distinct struct {
+	Field    string `json:"field"`
+	Type     string `json:"type"`     // string, float64 or bool, every index type of the field when empty
+	Counts   bool   `json:"counts"`   // return the number of docs per value
+	Original bool   `json:"original"` // strings as the doc with the lowest id holds them instead of lowercased, reads one doc per value
+}
+
+type DistinctValue struct {
+	Value interface{} `json:"value"`
+	Count int         `json:"count"`
+}
+
+func (d *Distinct) Check() (err error) {
+	if d.Field == "" {
+		return errors.New("distinct field is empty")
+	}
+	if d.Type == "" {
+		return
+	}
+	for _, valueType := range indexTypes {
+		if d.Type == valueType {
+			return
+		}
+	}
+
+	return errors.New("distinct type is unknown: " + d.Type)
+}
+
+// DistinctQuery walks the sorted field indexes, equal values sit next to each other
+func (db *Database) DistinctQuery(q Query) (result interface{}, err error) {
+	if q.Distinct == nil {
+		return nil, errors.New("distinct is empty")
+	}
+	distinct := q.Distinct
+	err = distinct.Check()
+	if err != nil {
+		return
+	}
+	t, err := db.LoadTable(&q.Table)
+	if err != nil {
+		return
+	}
+
+	var matched map[float64]bool
+	if q.HasFilter() {
+		idList, err := db.Filter(&q.Table, q.filter(), q.IndexMode, q.Stats)
+		if err != nil {
+			return nil, err
+		}
+		matched = make(map[float64]bool, len(idList))
+		for _, id := range idList {
+			matched[id] = true
+		}
+	}
+
+	valueTypes := indexTypes
+	if distinct.Type != "" {
+		valueTypes = []string{distinct.Type}
+	}
+	var values []DistinctValue
+	var firstIds []float64 // lowest doc id of every value
+	for _, valueType := range valueTypes {
+		ti, ok := t.Engine.LoadIndex(distinct.Field + "_" + valueType)
+		if !ok {
+			continue
+		}
+		start := len(values)
+		for _, item := range *ti {
+			if matched != nil && !matched[item.Id] {
+				continue
+			}
+			last := len(values) - 1
+			if last >= start && values[last].Value == item.Value {
+				values[last].Count++
+				if item.Id < firstIds[last] {
+					firstIds[last] = item.Id
+				}
+				continue
+			}
+			values = append(values, DistinctValue{Value: item.Value, Count: 1})
+			firstIds = append(firstIds, item.Id)
+		}
+	}
+	if q.Limit > 0 && len(values) > q.Limit {
+		values = values[:q.Limit]
+	}
+	if distinct.Original && !t.isCaseSensitive(distinct.Field) {
+		t.originalCase(distinct.Field, values, firstIds)
+	}
+
+	if distinct.Counts {
+		if values == nil {
+			values = []DistinctValue{}
+		}
+		return values, nil
+	}
+	list := make([]interface{}, len(values))
+	for i, value := range values {
+		list[i] = value.Value
+	}
+	result = list
+
+	return
+}
+
+// originalCase swaps lowercased index strings for the value of the doc they were taken from
+func (t *Table) originalCase(field string, values []DistinctValue, firstIds []float64) {
+	path := strings.Split(field, ".")
+	for i := range values {
+		value, ok := values[i].Value.(string)
+		if !ok {
+			continue
+		}
+		doc, ok := t.Engine.Get(firstIds[i])
+		if !ok {
+			continue
+		}
+		val, err := getVal(doc.Fields, path)
+		if original, ok := val.(string); err == nil && ok && strings.ToLower(original) == value {
+			values[i].Value = original
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package flexdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistinctCase(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":3,"city":"paris","code":"AB"}`,
+		`{"id":1,"city":"Paris","code":"ab"}`,
+		`{"id":2,"city":"ROME","code":"AB"}`,
+		`{"id":4,"city":"Rome","age":30}`,
+	)
+	if err := db.SetCaseSensitive("people", "code", true); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		distinct Distinct
+		want     interface{}
+	}{
+		{"lowercased", Distinct{Field: "city", Counts: true}, []DistinctValue{{"paris", 2}, {"rome", 2}}},
+		{"original lowest id wins", Distinct{Field: "city", Counts: true, Original: true}, []DistinctValue{{"Paris", 2}, {"ROME", 2}}},
+		{"original without counts", Distinct{Field: "city", Original: true}, []interface{}{"Paris", "ROME"}},
+		{"case sensitive field", Distinct{Field: "code", Counts: true}, []DistinctValue{{"AB", 2}, {"ab", 1}}},
+		{"numbers", Distinct{Field: "age", Counts: true}, []DistinctValue{{30.0, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			distinct := test.distinct
+			result, err := db.Run(&Query{Table: "people", Type: "distinct", Distinct: &distinct})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, test.want) {
+				t.Fatalf("values = %v, want %v", result, test.want)
+			}
+		})
+	}
+}
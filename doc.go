@@ -109,3 +109,9 @@ func (d *Doc) IsEmpty() bool {
 func (d *Doc) MarshalJSON() ([]byte, error) {
 	return json.Marshal(d.Fields)
 }
+
+func (d *Doc) UnmarshalJSON(data []byte) error {
+	d.Fields = make(map[string]interface{})
+
+	return json.Unmarshal(data, &d.Fields)
+}
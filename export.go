@@ -0,0 +1,121 @@
+package flexdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+const ImportBatchSize = 500
+
+type ImportError struct {
+	Line int
+	Err  error
+}
+
+func (e ImportError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+type ImportResult struct {
+	Added  int
+	Errors []ImportError
+}
+
+func (db *Database) ExportTable(tableName string, w io.Writer) (err error) {
+	t, err := db.LoadTable(&tableName)
+	if err != nil {
+		return
+	}
+	docs, err := t.sortedDocs()
+	if err != nil {
+		return
+	}
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	for i := range docs {
+		err = enc.Encode(&docs[i])
+		if err != nil {
+			return
+		}
+	}
+
+	return buf.Flush()
+}
+
+func (db *Database) ImportTable(tableName string, r io.Reader) (result ImportResult, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var batch []importLine
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		doc := NewDoc()
+		err := json.Unmarshal(scanner.Bytes(), doc)
+		if err != nil {
+			result.Errors = append(result.Errors, ImportError{Line: lineNumber, Err: err})
+			continue
+		}
+		batch = append(batch, importLine{line: lineNumber, doc: doc})
+		if len(batch) == ImportBatchSize {
+			db.importBatch(&tableName, batch, &result)
+			batch = batch[:0]
+		}
+	}
+	db.importBatch(&tableName, batch, &result)
+	err = scanner.Err()
+
+	// parse errors are found before the add errors of their batch
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Line < result.Errors[j].Line
+	})
+
+	return
+}
+
+type importLine struct {
+	line int
+	doc  *Doc
+}
+
+func (db *Database) importBatch(tableName *string, batch []importLine, result *ImportResult) {
+	if len(batch) == 0 {
+		return
+	}
+
+	// new ids come from the id index, so it has to be up to date
+	db.WaitIndexes()
+	nextId := float64(1)
+	if t, err := db.LoadTable(tableName); err == nil {
+		if ti, ok := t.Engine.LoadIndex("id_float64"); ok && len(*ti) > 0 {
+			nextId = (*ti)[len(*ti)-1].Id + 1
+		}
+	}
+	for _, item := range batch {
+		var err error
+		if id, ok := item.doc.Fields["id"]; ok {
+			err = item.doc.SetId(id)
+		} else {
+			err = item.doc.SetId(nextId)
+		}
+		if err == nil {
+			err = db.Add(tableName, item.doc)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, ImportError{Line: item.line, Err: err})
+			continue
+		}
+		id, _ := item.doc.GetId()
+		if id >= nextId {
+			nextId = id + 1
+		}
+		result.Added++
+	}
+}
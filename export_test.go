@@ -0,0 +1,77 @@
+package flexdb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportTable(t *testing.T) {
+	db := NewDb()
+	data := strings.Join([]string{
+		`{"id":1,"name":"ann"}`,
+		`{"id":1,"name":"bob"}`,
+		`{"id":2,"name":`,
+		`{"id":1,"name":"cat"}`,
+		``,
+		`{"name":"dan"}`,
+		`not json`,
+	}, "\n")
+	result, err := db.ImportTable("people", strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 2 {
+		t.Fatalf("added = %d, want 2", result.Added)
+	}
+	var lines []int
+	for _, lineErr := range result.Errors {
+		lines = append(lines, lineErr.Line)
+	}
+	if want := []int{2, 3, 4, 7}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("error lines = %v, want %v", lines, want)
+	}
+
+	// docs without id continue after the highest id
+	table := "people"
+	doc := Doc{Fields: map[string]interface{}{"id": 2.0}}
+	if err := db.Get(&table, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Fields["name"] != "dan" {
+		t.Fatalf("doc 2 = %v, want dan", doc.Fields)
+	}
+}
+
+func TestTableRoundTrip(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":2,"name":"bob","tags":["a","b"]}`,
+		`{"id":1,"name":"ann","address":{"city":"Paris"}}`,
+	)
+	var first bytes.Buffer
+	if err := db.ExportTable("people", &first); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"address":{"city":"Paris"},"id":1,"name":"ann"}` + "\n" +
+		`{"id":2,"name":"bob","tags":["a","b"]}` + "\n"
+	if first.String() != want {
+		t.Fatalf("export = %q, want %q", first.String(), want)
+	}
+
+	imported := NewDb()
+	result, err := imported.ImportTable("people", bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 2 || len(result.Errors) != 0 {
+		t.Fatalf("result = %+v, want 2 added", result)
+	}
+	var second bytes.Buffer
+	if err := imported.ExportTable("people", &second); err != nil {
+		t.Fatal(err)
+	}
+	if second.String() != first.String() {
+		t.Fatalf("export after round trip = %q, want %q", second.String(), first.String())
+	}
+}
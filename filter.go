@@ -0,0 +1,89 @@
+package flexdb
+
+import "time"
+
+// Filter is a tree of conditions, a leaf holds Where and a group holds Filters
+type Filter struct {
+	Type    string   `json:"type"` // and, or, not
+	Filters []Filter `json:"filters"`
+	Where   *Where   `json:"where"`
+}
+
+// filter merges Query.Filter with the flat Where list and WhereType
+func (q *Query) filter() *Filter {
+	if len(q.Where) == 0 {
+		return q.Filter
+	}
+
+	flat := Filter{
+		Type: q.WhereType,
+	}
+	for i := range q.Where {
+		flat.Filters = append(flat.Filters, Filter{Where: &q.Where[i]})
+	}
+	if q.Filter == nil {
+		return &flat
+	}
+
+	return &Filter{
+		Type:    "and",
+		Filters: []Filter{flat, *q.Filter},
+	}
+}
+
+func (q *Query) HasFilter() bool {
+	return len(q.Where) != 0 || q.Filter != nil
+}
+
+func (db *Database) Filter(tableName *string, filter *Filter, indexMode string, stats *Stats) (res []float64, err error) {
+	defer stats.track(phaseWhere, time.Now())
+
+	t, err := db.LoadTable(tableName)
+	if err != nil {
+		return
+	}
+
+	return t.filterIds(filter, indexMode, stats)
+}
+
+func (t *Table) filterIds(filter *Filter, indexMode string, stats *Stats) (ids []float64, err error) {
+	if filter == nil {
+		return []float64{}, nil
+	}
+	plan, err := t.plan(filter, indexMode)
+	if err != nil {
+		return
+	}
+
+	return t.runPlan(plan, indexMode, stats)
+}
+
+// count is the number of docs in the table, read from the id index length
+func (t *Table) count() int {
+	ti, ok := t.Engine.LoadIndex("id_float64")
+	if !ok {
+		return 0
+	}
+
+	return len(*ti)
+}
+
+// allIds lists every doc id of the table except the ones in exclude
+func (t *Table) allIds(exclude []float64) (ids []float64) {
+	excluded := make(map[float64]bool, len(exclude))
+	for _, id := range exclude {
+		excluded[id] = true
+	}
+	ids = []float64{}
+	ti, ok := t.Engine.LoadIndex("id_float64")
+	if !ok {
+		return
+	}
+	for _, item := range *ti {
+		if !excluded[item.Id] {
+			ids = append(ids, item.Id)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,74 @@
+package flexdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterTree(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":1,"city":"paris","age":20}`,
+		`{"id":2,"city":"rome","age":30}`,
+		`{"id":3,"city":"paris","age":40}`,
+		`{"id":4,"city":"oslo","age":50}`,
+	)
+	paris := Where{Field: "city", Value: "paris"}
+	rome := Where{Field: "city", Value: "rome"}
+	old := Where{Field: "age", Operator: ">", Value: 25.0}
+	tests := []struct {
+		name      string
+		where     []Where
+		whereType string
+		filter    *Filter
+		want      []float64
+	}{
+		{"or", nil, "", &Filter{Type: "or", Filters: []Filter{{Where: &paris}, {Where: &rome}}}, []float64{1, 2, 3}},
+		{"not", nil, "", &Filter{Type: "not", Filters: []Filter{{Where: &paris}}}, []float64{2, 4}},
+		{"and of or", nil, "", &Filter{Type: "and", Filters: []Filter{
+			{Type: "or", Filters: []Filter{{Where: &paris}, {Where: &rome}}},
+			{Where: &old},
+		}}, []float64{2, 3}},
+		{"not of and", nil, "", &Filter{Type: "not", Filters: []Filter{
+			{Type: "and", Filters: []Filter{{Where: &paris}, {Where: &old}}},
+		}}, []float64{1, 2, 4}},
+		{"legacy and filter", []Where{old}, "", &Filter{Type: "not", Filters: []Filter{{Where: &rome}}}, []float64{3, 4}},
+		{"legacy or and filter", []Where{paris, rome}, "or", &Filter{Where: &old}, []float64{2, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := db.Run(&Query{Table: "people", Type: "mget", Where: test.where, WhereType: test.whereType, Filter: test.filter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := docIds(t, result); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ids = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	db := newTestTable(t, "people", `{"id":1,"city":"paris"}`)
+	paris := Where{Field: "city", Value: "paris"}
+	tests := []struct {
+		name      string
+		where     []Where
+		whereType string
+		filter    *Filter
+	}{
+		{"unknown where type", []Where{paris}, "bogus", nil},
+		{"type on a leaf", nil, "", &Filter{Type: "not", Where: &paris}},
+		{"unknown type on a leaf", nil, "", &Filter{Type: "bogus", Where: &paris}},
+		{"filters on a leaf", nil, "", &Filter{Where: &paris, Filters: []Filter{{Where: &paris}}}},
+		{"unknown group type", nil, "", &Filter{Type: "bogus", Filters: []Filter{{Where: &paris}}}},
+		{"not with two filters", nil, "", &Filter{Type: "not", Filters: []Filter{{Where: &paris}, {Where: &paris}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := db.Run(&Query{Table: "people", Type: "count", Where: test.where, WhereType: test.whereType, Filter: test.filter})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package flexdb
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Group struct {
+	Fields    []string    `json:"fields"` // field paths to group by
+	Aggregate []Aggregate `json:"aggregate"`
+	Sort      string      `json:"sort"`      // aggregate key to order groups by, group fields when empty
+	Direction string      `json:"direction"` // asc or desc, asc when empty
+	Limit     int         `json:"limit"`     // max number of groups, all when zero
+}
+
+type GroupResult struct {
+	Key    map[string]interface{} `json:"key"`
+	Values map[string]interface{} `json:"values"`
+}
+
+type groupState struct {
+	key    []interface{}
+	states []aggregateState
+}
+
+func (g *Group) Check() (err error) {
+	if len(g.Fields) == 0 {
+		return errors.New("group fields are empty")
+	}
+	if g.Direction != "" && g.Direction != "asc" && g.Direction != "desc" {
+		return errors.New("group direction is unknown: " + g.Direction)
+	}
+	sortFound := g.Sort == ""
+	for i := range g.Aggregate {
+		err = g.Aggregate[i].Check()
+		if err != nil {
+			return
+		}
+		if g.Aggregate[i].Key() == g.Sort {
+			sortFound = true
+		}
+	}
+	if !sortFound {
+		err = errors.New("group sort is not an aggregate: " + g.Sort)
+	}
+
+	return
+}
+
+func (db *Database) GroupQuery(q Query) (result interface{}, err error) {
+	if q.Group == nil {
+		return nil, errors.New("group is empty")
+	}
+	group := q.Group
+	err = group.Check()
+	if err != nil {
+		return
+	}
+	t, err := db.LoadTable(&q.Table)
+	if err != nil {
+		return
+	}
+
+	var idList []float64
+	if q.HasFilter() {
+		idList, err = db.Filter(&q.Table, q.filter(), q.IndexMode, q.Stats)
+		if err != nil {
+			return
+		}
+	} else {
+		idList = t.allIds(nil)
+	}
+
+	paths := make([][]string, len(group.Fields))
+	for i, field := range group.Fields {
+		paths[i] = strings.Split(field, ".")
+	}
+	start := time.Now()
+	groups := make(map[string]*groupState)
+	for _, id := range idList {
+		doc, ok := t.Engine.Get(id)
+		if !ok {
+			continue
+		}
+		q.Stats.fetch(1)
+		key := make([]interface{}, len(paths))
+		for i, path := range paths {
+			key[i], _ = getVal(doc.Fields, path)
+		}
+		keyData, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		state, ok := groups[string(keyData)]
+		if !ok {
+			state = &groupState{
+				key:    key,
+				states: make([]aggregateState, len(group.Aggregate)),
+			}
+			groups[string(keyData)] = state
+		}
+		for i, aggregate := range group.Aggregate {
+			if aggregate.Func == "count" && aggregate.Field == "" {
+				state.states[i].count++
+				continue
+			}
+			val, err := getVal(doc.Fields, strings.Split(aggregate.Field, "."))
+			if value, ok := val.(float64); err == nil && ok {
+				state.states[i].add(value)
+			}
+		}
+	}
+
+	q.Stats.track(phaseMultiGet, start)
+
+	// groups ordered by key, then by the sort aggregate with missing values last
+	var groupKeys []string
+	for key := range groups {
+		groupKeys = append(groupKeys, key)
+	}
+	sort.Strings(groupKeys)
+	states := make([]*groupState, 0, len(groupKeys))
+	for _, key := range groupKeys {
+		states = append(states, groups[key])
+	}
+	keyOrder := make([]Order, len(group.Fields))
+	sort.SliceStable(states, func(i, j int) bool {
+		return compareRows(keyOrder, states[i].key, 0, states[j].key, 0) < 0
+	})
+
+	results := make([]GroupResult, 0, len(states))
+	for _, state := range states {
+		groupResult := GroupResult{
+			Key:    make(map[string]interface{}, len(group.Fields)),
+			Values: make(map[string]interface{}, len(group.Aggregate)),
+		}
+		for i, field := range group.Fields {
+			groupResult.Key[field] = state.key[i]
+		}
+		for i, aggregate := range group.Aggregate {
+			groupResult.Values[aggregate.Key()] = state.states[i].result(aggregate.Func)
+		}
+		results = append(results, groupResult)
+	}
+	if group.Sort != "" {
+		sortOrder := []Order{{Direction: group.Direction}}
+		sort.SliceStable(results, func(i, j int) bool {
+			first := []interface{}{toSortValue(results[i].Values[group.Sort])}
+			second := []interface{}{toSortValue(results[j].Values[group.Sort])}
+			return compareRows(sortOrder, first, 0, second, 0) < 0
+		})
+	}
+	if group.Limit > 0 && len(results) > group.Limit {
+		results = results[:group.Limit]
+	}
+	result = results
+
+	return
+}
+
+func toSortValue(value interface{}) interface{} {
+	if count, ok := value.(int); ok {
+		return float64(count)
+	}
+
+	return value
+}
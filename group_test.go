@@ -0,0 +1,60 @@
+package flexdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupQuery(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":1,"address":null,"age":20}`,
+		`{"id":2,"address":{"city":"Paris"},"age":30}`,
+		`{"id":3,"address":{"city":"Rome"},"age":40}`,
+		`{"id":4,"address":{"city":"Paris"},"age":50}`,
+	)
+	tests := []struct {
+		name  string
+		group Group
+		want  []GroupResult
+	}{
+		{
+			name: "null parent groups as missing",
+			group: Group{
+				Fields:    []string{"address.city"},
+				Aggregate: []Aggregate{{Func: "count"}},
+			},
+			want: []GroupResult{
+				{Key: map[string]interface{}{"address.city": "Paris"}, Values: map[string]interface{}{"count": 2}},
+				{Key: map[string]interface{}{"address.city": "Rome"}, Values: map[string]interface{}{"count": 1}},
+				{Key: map[string]interface{}{"address.city": nil}, Values: map[string]interface{}{"count": 1}},
+			},
+		},
+		{
+			name: "sorted by aggregate with limit",
+			group: Group{
+				Fields:    []string{"address.city"},
+				Aggregate: []Aggregate{{Func: "avg", Field: "age"}},
+				Sort:      "avg_age",
+				Direction: "desc",
+				Limit:     2,
+			},
+			want: []GroupResult{
+				// ties keep the group key order
+				{Key: map[string]interface{}{"address.city": "Paris"}, Values: map[string]interface{}{"avg_age": 40.0}},
+				{Key: map[string]interface{}{"address.city": "Rome"}, Values: map[string]interface{}{"avg_age": 40.0}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			group := test.group
+			result, err := db.Run(&Query{Table: "people", Type: "group", Group: &group})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, test.want) {
+				t.Fatalf("group = %v, want %v", result, test.want)
+			}
+		})
+	}
+}
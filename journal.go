@@ -0,0 +1,209 @@
+package flexdb
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// JournalEntry is a mutation with its place in history, unlike the log the journal is never truncated
+type JournalEntry struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	BucketItem
+}
+
+type Journal struct {
+	wal *Wal
+	seq uint64
+	mu  sync.Mutex
+}
+
+type RestorePoint struct {
+	Seq  uint64    // last sequence to apply, zero means no limit
+	Time time.Time // apply entries recorded up to this time, zero means no limit
+}
+
+var errStopReplay = errors.New("stop replay")
+
+func OpenJournal(path string, crypter *Crypter) (j *Journal, err error) {
+	wal, err := OpenWal(path)
+	if err != nil {
+		return
+	}
+	wal.SetCrypter(crypter)
+	j = &Journal{
+		wal: wal,
+	}
+
+	// continue sequence from the last entry
+	err = wal.ReplayRecords(func(record []byte) error {
+		var entry JournalEntry
+		err := json.Unmarshal(record, &entry)
+		if err != nil {
+			return err
+		}
+		j.seq = entry.Seq
+		return nil
+	})
+	if err != nil {
+		_ = wal.Close()
+		return nil, err
+	}
+
+	return
+}
+
+func (j *Journal) Write(item BucketItem) (err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry := JournalEntry{
+		Seq:        j.seq + 1,
+		Time:       time.Now().UTC(),
+		BucketItem: item,
+	}
+	err = j.wal.WriteRecord(&entry)
+	if err != nil {
+		return
+	}
+	j.seq = entry.Seq
+
+	return
+}
+
+func (j *Journal) Seq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.seq
+}
+
+func (j *Journal) Sync() error {
+	return j.wal.Sync()
+}
+
+func (j *Journal) Close() error {
+	return j.wal.Close()
+}
+
+// RestoreJournal builds a new in-memory Database from the journal in the data directory at path,
+// starting at the docs the database held when the journal was created and stopping at point.
+// Inspect it, then hand it to SwapIn to roll the live database back.
+func RestoreJournal(path string, options Options, point RestorePoint) (db *Database, err error) {
+	var crypter *Crypter
+	if options.EncryptionKey != nil {
+		crypter, err = NewCrypter(options.EncryptionKey)
+		if err != nil {
+			return
+		}
+	}
+	file, err := os.Open(filepath.Join(path, "journal.log"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	db, err = loadSnapshotFile(filepath.Join(path, "journal.base"), crypter, nil)
+	if err != nil {
+		return
+	}
+	_, _, err = readRecords(file, crypter, func(record []byte) error {
+		var entry JournalEntry
+		err := json.Unmarshal(record, &entry)
+		if err != nil {
+			return err
+		}
+		if point.Seq != 0 && entry.Seq > point.Seq {
+			return errStopReplay
+		}
+		if !point.Time.IsZero() && entry.Time.After(point.Time) {
+			return errStopReplay
+		}
+		return db.apply(&entry.BucketItem)
+	})
+	if err == errStopReplay {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// SwapIn makes db hold exactly the docs of restored, writing the difference
+// through Add, Replace and Delete so log and journal stay in step
+func (db *Database) SwapIn(restored *Database) (err error) {
+	type change struct {
+		table string
+		doc   Doc
+		exist bool
+	}
+	var deletes, writes []change
+
+	db.Tables.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		restoredTable, _ := restored.LoadTable(&name)
+		err = value.(*Table).Engine.Scan(func(id float64, doc Doc) bool {
+			if restoredTable != nil {
+				if _, ok := restoredTable.Engine.Get(id); ok {
+					return true
+				}
+			}
+			deletes = append(deletes, change{table: name, doc: doc})
+			return true
+		})
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+	restored.Tables.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		currentTable, _ := db.LoadTable(&name)
+		err = value.(*Table).Engine.Scan(func(id float64, doc Doc) bool {
+			exist := false
+			if currentTable != nil {
+				var current Doc
+				current, exist = currentTable.Engine.Get(id)
+				if exist && reflect.DeepEqual(current.Fields, doc.Fields) {
+					return true
+				}
+			}
+			writes = append(writes, change{table: name, doc: doc, exist: exist})
+			return true
+		})
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+
+	for _, item := range deletes {
+		id, _ := item.doc.GetId()
+		doc := NewDoc()
+		_ = doc.SetId(id)
+		err = db.Delete(&item.table, doc)
+		if err != nil {
+			return
+		}
+	}
+	for _, item := range writes {
+		doc := item.doc
+		if item.exist {
+			err = db.Replace(&item.table, &doc)
+		} else {
+			err = db.Add(&item.table, &doc)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
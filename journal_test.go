@@ -0,0 +1,77 @@
+package flexdb
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSwapInKeepsDocsFromBeforeJournal(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    []byte
+		newKey []byte // rotated after the journal was created
+	}{
+		{"plain", nil, nil},
+		{"encrypted", bytes.Repeat([]byte{1}, 32), nil},
+		{"rotated key", bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			table := "people"
+			db, err := Open(dir, Options{EncryptionKey: test.key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 2; i++ {
+				if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": float64(i)}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			options := Options{EncryptionKey: test.key, Journal: true}
+			db, err = Open(dir, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": 3.0}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Delete(&table, &Doc{Fields: map[string]interface{}{"id": 1.0}}); err != nil {
+				t.Fatal(err)
+			}
+			if test.newKey != nil {
+				if err := <-db.RotateKey(test.newKey); err != nil {
+					t.Fatal(err)
+				}
+				options.EncryptionKey = test.newKey
+			}
+			db.WaitIndexes()
+
+			// roll back the delete
+			restored, err := RestoreJournal(dir, options, RestorePoint{Seq: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.SwapIn(restored); err != nil {
+				t.Fatal(err)
+			}
+			db.WaitIndexes()
+			result, err := db.Run(&Query{Table: table, Type: "mget"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []float64{1, 2, 3}
+			if got := docIds(t, result); !reflect.DeepEqual(got, want) {
+				t.Fatalf("ids = %v, want %v", got, want)
+			}
+		})
+	}
+}
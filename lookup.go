@@ -0,0 +1,136 @@
+package flexdb
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+type Lookup struct {
+	Table        string `json:"table"`         // table to fetch the docs from
+	LocalField   string `json:"local_field"`   // field path of the result doc
+	ForeignField string `json:"foreign_field"` // indexed field path of the other table
+	As           string `json:"as"`            // top level field the matching docs go under, Table when empty
+}
+
+func (l *Lookup) Key() string {
+	if l.As != "" {
+		return l.As
+	}
+
+	return l.Table
+}
+
+func (l *Lookup) Check() (err error) {
+	if l.Table == "" {
+		return errors.New("lookup table is empty")
+	}
+	if l.LocalField == "" || l.ForeignField == "" {
+		return errors.New("lookup fields are empty for table: " + l.Table)
+	}
+
+	return
+}
+
+func (q *Query) CheckLookup() (err error) {
+	for i := range q.Lookup {
+		err = q.Lookup[i].Check()
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// lookupDocs embeds the matching docs of every lookup table into docs,
+// each distinct local value is looked up once in the foreign index
+func (db *Database) lookupDocs(docs []Doc, lookups []Lookup, stats *Stats) (result []Doc, err error) {
+	if len(lookups) == 0 || len(docs) == 0 {
+		return docs, nil
+	}
+	defer stats.track(phaseMultiGet, time.Now())
+
+	// stored docs are never modified
+	result = make([]Doc, len(docs))
+	for i, doc := range docs {
+		fields := make(map[string]interface{}, len(doc.Fields)+len(lookups))
+		for key, value := range doc.Fields {
+			fields[key] = value
+		}
+		result[i] = Doc{Fields: fields}
+	}
+
+	for _, lookup := range lookups {
+		t, err := db.LoadTable(&lookup.Table)
+		if err != nil {
+			return nil, err
+		}
+		path := strings.Split(lookup.LocalField, ".")
+		found := make(map[string][]interface{})
+		for _, doc := range result {
+			matched := []interface{}{}
+			val, err := getVal(doc.Fields, path)
+			if err == nil && val != nil {
+				// a list matches the docs of every value in it
+				values, ok := val.([]interface{})
+				if !ok {
+					values = []interface{}{val}
+				}
+				for _, value := range values {
+					foreignDocs, err := db.lookupValue(t, lookup, value, found, stats)
+					if err != nil {
+						return nil, err
+					}
+					matched = append(matched, foreignDocs...)
+				}
+			}
+			doc.Fields[lookup.Key()] = matched
+		}
+	}
+
+	return
+}
+
+func (db *Database) lookupValue(t *Table, lookup Lookup, value interface{}, found map[string][]interface{}, stats *Stats) (foreignDocs []interface{}, err error) {
+	if value == nil {
+		return
+	}
+	key, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if foreignDocs, ok := found[string(key)]; ok {
+		return foreignDocs, nil
+	}
+
+	where := Where{Field: lookup.ForeignField, Value: value}
+	plan, err := t.planWhere(&where, "")
+	if err != nil {
+		return
+	}
+	idList, err := t.runPlan(plan, "", stats)
+	if err != nil {
+		return
+	}
+	idList, err = t.sortIds(idList, nil)
+	if err != nil {
+		return
+	}
+	for _, id := range idList {
+		doc, ok := t.Engine.Get(id)
+		if !ok {
+			continue
+		}
+		fields := make(map[string]interface{}, len(doc.Fields))
+		for key, value := range doc.Fields {
+			fields[key] = value
+		}
+		foreignDocs = append(foreignDocs, fields)
+	}
+	stats.fetch(len(foreignDocs))
+	found[string(key)] = foreignDocs
+
+	return
+}
@@ -0,0 +1,53 @@
+package flexdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupDocs(t *testing.T) {
+	db := newTestTable(t, "customers",
+		`{"id":1,"name":"Ann"}`,
+		`{"id":2,"name":"Bob"}`,
+	)
+	orders := "orders"
+	for _, data := range []map[string]interface{}{
+		{"id": 1.0, "buyer": map[string]interface{}{"customer": 2.0}},
+		{"id": 2.0, "buyer": nil},
+		{"id": 3.0, "buyer": map[string]interface{}{"customer": []interface{}{1.0, 2.0}}},
+	} {
+		if err := db.Add(&orders, &Doc{Fields: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.WaitIndexes()
+
+	result, err := db.Run(&Query{
+		Table:  orders,
+		Type:   "all",
+		Lookup: []Lookup{{Table: "customers", LocalField: "buyer.customer", ForeignField: "id", As: "customers"}},
+		Fields: []string{"customers"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ann := map[string]interface{}{"id": 1.0, "name": "Ann"}
+	bob := map[string]interface{}{"id": 2.0, "name": "Bob"}
+	want := []Doc{
+		{Fields: map[string]interface{}{"id": 1.0, "customers": []interface{}{bob}}},
+		{Fields: map[string]interface{}{"id": 2.0, "customers": []interface{}{}}},
+		{Fields: map[string]interface{}{"id": 3.0, "customers": []interface{}{ann, bob}}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("lookup = %v, want %v", result, want)
+	}
+
+	// stored docs are never modified
+	stored := Doc{Fields: map[string]interface{}{"id": 1.0}}
+	if err := db.Get(&orders, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stored.Fields["customers"]; ok {
+		t.Fatal("lookup modified the stored doc")
+	}
+}
@@ -0,0 +1,97 @@
+package flexdb
+
+import (
+	"fmt"
+	"time"
+)
+
+type Durability int8
+
+const (
+	// NoSync leaves flushing to the operating system, a power loss may drop recent writes
+	NoSync Durability = iota
+	// GroupCommit flushes the log to disk every Options.SyncInterval
+	GroupCommit
+	// SyncEveryWrite flushes the log to disk before a write returns
+	SyncEveryWrite
+)
+
+type Options struct {
+	Durability      Durability            // default for tables not listed in TableDurability
+	TableDurability map[string]Durability //map[tableName]Durability
+	SyncInterval    time.Duration         // group commit interval, one second when zero
+	Checkpoint      CheckpointConfig
+	EncryptionKey   []byte                   // encrypts snapshot and log with AES-GCM when set
+	Journal         bool                     // keep every mutation in journal.log for RestoreJournal
+	CaseSensitive   map[string][]string      //map[tableName][]field, string fields matched with their original case
+	Engines         map[string]StorageEngine //map[tableName]StorageEngine, tables not listed are kept in memory
+}
+
+func (o *Options) durability(tableName string) Durability {
+	if mode, ok := o.TableDurability[tableName]; ok {
+		return mode
+	}
+
+	return o.Durability
+}
+
+func (o *Options) groupCommit() bool {
+	if o.Durability == GroupCommit {
+		return true
+	}
+	for _, mode := range o.TableDurability {
+		if mode == GroupCommit {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sync forces every logged write to disk regardless of durability mode
+func (db *Database) Sync() (err error) {
+	if db.Wal == nil {
+		return
+	}
+	err = db.Wal.Sync()
+	if err != nil || db.Journal == nil {
+		return
+	}
+
+	return db.Journal.Sync()
+}
+
+func (db *Database) startSyncer() {
+	interval := db.Options.SyncInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	db.syncStop = make(chan struct{})
+	db.syncDone = make(chan struct{})
+
+	go func() {
+		defer close(db.syncDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-db.syncStop:
+				return
+			case <-ticker.C:
+				err := db.Sync()
+				if err != nil {
+					fmt.Println(err)
+				}
+			}
+		}
+	}()
+}
+
+func (db *Database) stopSyncer() {
+	if db.syncStop == nil {
+		return
+	}
+	close(db.syncStop)
+	<-db.syncDone
+	db.syncStop = nil
+}
@@ -0,0 +1,132 @@
+package flexdb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func (w *Wal) isDirty() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.dirty
+}
+
+// TestDurabilityPowerLoss drops every log byte that was not synced when the
+// power went out, what is left is what each mode promises to keep
+func TestDurabilityPowerLoss(t *testing.T) {
+	tests := []struct {
+		name string
+		mode Durability
+		want []float64
+	}{
+		// every write is on disk before Add returns
+		{"sync every write", SyncEveryWrite, []float64{1, 2, 3}},
+		// writes reach the disk on the next tick, the last one is still waiting
+		{"group commit", GroupCommit, []float64{1, 2}},
+		// nothing is synced until Close
+		{"no sync", NoSync, []float64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			options := Options{Durability: test.mode, SyncInterval: 50 * time.Millisecond}
+			db, err := Open(dir, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			table := "people"
+			var synced int64
+			add := func(id float64) {
+				if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": id}}); err != nil {
+					t.Fatal(err)
+				}
+				if !db.Wal.isDirty() {
+					synced = db.Wal.Size()
+				}
+			}
+
+			add(1)
+			add(2)
+			// wait longer than the group commit interval
+			time.Sleep(150 * time.Millisecond)
+			if !db.Wal.isDirty() {
+				synced = db.Wal.Size()
+			}
+			add(3)
+			if dirty := db.Wal.isDirty(); dirty != (test.mode != SyncEveryWrite) {
+				t.Fatalf("unsynced write after Add = %v", dirty)
+			}
+			db.WaitIndexes()
+			crash(db)
+
+			if err := os.Truncate(filepath.Join(dir, "wal.log"), synced); err != nil {
+				t.Fatal(err)
+			}
+			db, err = Open(dir, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			got := []float64{}
+			// a table is only there once one of its writes survived
+			if _, err := db.LoadTable(&table); err == nil {
+				result, err := db.Run(&Query{Table: table, Type: "mget"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = docIds(t, result)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ids = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestDurabilityCrash kills the process without losing the page cache, a line
+// cut by the crash is dropped and every complete write survives in any mode
+func TestDurabilityCrash(t *testing.T) {
+	for _, mode := range []Durability{SyncEveryWrite, GroupCommit, NoSync} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		options := Options{Durability: mode}
+		db, err := Open(dir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table := "people"
+		for i := 1; i <= 3; i++ {
+			if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": float64(i)}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		db.WaitIndexes()
+		crash(db)
+
+		path := filepath.Join(dir, "wal.log")
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Truncate(path, info.Size()-5); err != nil {
+			t.Fatal(err)
+		}
+		db, err = Open(dir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := db.Run(&Query{Table: table, Type: "mget"})
+		_ = db.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := docIds(t, result); !reflect.DeepEqual(got, []float64{1, 2}) {
+			t.Fatalf("mode %d: ids = %v, want [1 2]", mode, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package flexdb
+
+import (
+	"errors"
+	"sort"
+)
+
+func (db *Database) Sort(tableName *string, list []float64, keys []Order, limit int) (sortedList []float64, err error) {
+	t, err := db.LoadTable(tableName)
+	if err != nil {
+		return
+	}
+	sortedList, err = t.sortIds(list, keys)
+	if err != nil {
+		return
+	}
+	if limit > 0 && len(sortedList) > limit {
+		sortedList = sortedList[:limit]
+	}
+
+	return
+}
+
+func (t *Table) sortIds(list []float64, keys []Order) (sorted []float64, err error) {
+	sorted = append([]float64{}, list...)
+	if len(sorted) <= 1 {
+		return
+	}
+	for _, key := range keys {
+		if key.Direction != "" && key.Direction != "asc" && key.Direction != "desc" {
+			return nil, errors.New("order direction is unknown: " + key.Direction)
+		}
+	}
+
+	// ids are unique, no need to look at the index
+	if len(keys) == 0 || (len(keys) == 1 && keys[0].Field == "id" && keys[0].Type == "float64") {
+		sort.Float64s(sorted)
+		if len(keys) == 1 && keys[0].Direction == "desc" {
+			sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+		}
+		return
+	}
+
+	rows, err := t.sortRows(sorted, keys)
+	if err != nil {
+		return
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return compareRows(keys, rows[sorted[i]], sorted[i], rows[sorted[j]], sorted[j]) < 0
+	})
+
+	return
+}
+
+// walkSorted calls fn with every id in the order of a single key without sorting the table,
+// equal values come by id like compareRows and docs without the key come last
+func (t *Table) walkSorted(key Order, fn func(id float64, value interface{}) bool) (err error) {
+	if key.Direction != "" && key.Direction != "asc" && key.Direction != "desc" {
+		return errors.New("order direction is unknown: " + key.Direction)
+	}
+	ti, ok := t.Engine.LoadIndex(key.Field + "_" + key.Type)
+	if !ok {
+		return errors.New("index with field path and type [" + key.Field + " " + key.Type + "] not exist")
+	}
+
+	index := *ti
+	for i := 0; i < len(index); {
+		// equal values are kept in insert order, not by id
+		from := i
+		if key.Direction == "desc" {
+			from = len(index) - 1 - i
+		}
+		to := from + 1
+		for to < len(index) && index[to].Value == index[from].Value {
+			to++
+		}
+		for from > 0 && index[from-1].Value == index[to-1].Value {
+			from--
+		}
+		group := make([]float64, 0, to-from)
+		for _, item := range index[from:to] {
+			group = append(group, item.Id)
+		}
+		sort.Float64s(group)
+		for _, id := range group {
+			if !fn(id, index[from].Value) {
+				return
+			}
+		}
+		i += to - from
+	}
+	if key.Field == "id" && key.Type == "float64" {
+		return
+	}
+
+	indexed := make(map[float64]bool, len(index))
+	for _, item := range index {
+		indexed[item.Id] = true
+	}
+	for _, id := range t.allIds(nil) {
+		if !indexed[id] && !fn(id, nil) {
+			return
+		}
+	}
+
+	return
+}
+
+// sortRows reads the value of every key for the listed ids from the indexes, nil when a doc misses it
+func (t *Table) sortRows(list []float64, keys []Order) (rows map[float64][]interface{}, err error) {
+	rows = make(map[float64][]interface{}, len(list))
+	for _, id := range list {
+		rows[id] = make([]interface{}, len(keys))
+	}
+	for i, key := range keys {
+		ti, ok := t.Engine.LoadIndex(key.Field + "_" + key.Type)
+		if !ok {
+			return nil, errors.New("index with field path and type [" + key.Field + " " + key.Type + "] not exist")
+		}
+		for _, item := range *ti {
+			if row, ok := rows[item.Id]; ok {
+				row[i] = item.Value
+			}
+		}
+	}
+
+	return
+}
+
+// compareRows orders two rows key by key, missing values go last and ties fall back to id
+func compareRows(keys []Order, first []interface{}, firstId float64, second []interface{}, secondId float64) int {
+	for i, key := range keys {
+		if first[i] == nil || second[i] == nil {
+			if first[i] == second[i] {
+				continue
+			}
+			if first[i] != nil {
+				return -1
+			}
+			return 1
+		}
+
+		result := 0
+		if compareInterface(first[i], "<", second[i]) {
+			result = -1
+		} else if compareInterface(first[i], ">", second[i]) {
+			result = 1
+		}
+		if key.Direction == "desc" {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+
+	if firstId < secondId {
+		return -1
+	}
+	if firstId > secondId {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,97 @@
+package flexdb
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCompareRows(t *testing.T) {
+	asc := []Order{{Field: "a", Type: "float64"}}
+	desc := []Order{{Field: "a", Type: "float64", Direction: "desc"}}
+	tests := []struct {
+		name   string
+		keys   []Order
+		first  []interface{}
+		second []interface{}
+		want   int
+	}{
+		{"asc less", asc, []interface{}{1.0}, []interface{}{2.0}, -1},
+		{"desc less", desc, []interface{}{1.0}, []interface{}{2.0}, 1},
+		{"tie falls back to id", desc, []interface{}{1.0}, []interface{}{1.0}, -1},
+		{"missing last", asc, []interface{}{nil}, []interface{}{2.0}, 1},
+		{"missing last desc", desc, []interface{}{2.0}, []interface{}{nil}, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := compareRows(test.keys, test.first, 1, test.second, 2); result != test.want {
+				t.Fatalf("compareRows = %v, want %v", result, test.want)
+			}
+		})
+	}
+}
+
+// TestAllSortedWalk checks the single key index walk of all against a full sort
+func TestAllSortedWalk(t *testing.T) {
+	var docs []string
+	for i := 1; i <= 20; i++ {
+		switch {
+		case i%5 == 0:
+			docs = append(docs, fmt.Sprintf(`{"id":%d}`, i))
+		default:
+			docs = append(docs, fmt.Sprintf(`{"id":%d,"rank":%d,"name":"n%d"}`, i, i%4, i%3))
+		}
+	}
+	table := "items"
+	db := newTestTable(t, table, docs...)
+	tb, err := db.LoadTable(&table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []Order{
+		{Field: "rank", Type: "float64"},
+		{Field: "rank", Type: "float64", Direction: "desc"},
+		{Field: "name", Type: "string", Direction: "desc"},
+		{Field: "id", Type: "float64", Direction: "desc"},
+	} {
+		keys := []Order{key}
+		sorted, err := tb.sortIds(tb.allIds(nil), keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, offset := range []int{0, 3, 18, 25} {
+			t.Run(fmt.Sprintf("%s %s offset %d", key.Field, key.Direction, offset), func(t *testing.T) {
+				want, _, err := db.Page(&table, sorted, keys, offset, 4, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, _, err := tb.pageSorted(Query{Sort: keys, Offset: offset, Limit: 4})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, append([]float64{}, want...)) {
+					t.Fatalf("page = %v, want %v", got, want)
+				}
+			})
+		}
+
+		// walking every cursor page visits the full sort once
+		var ids []float64
+		q := Query{Table: table, Type: "all", Sort: keys, Limit: 3}
+		for page := 0; page < 10; page++ {
+			result, err := db.Run(&q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, docIds(t, result)...)
+			if q.Next == "" {
+				break
+			}
+			q.Cursor = q.Next
+		}
+		if !reflect.DeepEqual(ids, sorted) {
+			t.Fatalf("%v cursor pages = %v, want %v", key, ids, sorted)
+		}
+	}
+}
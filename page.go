@@ -0,0 +1,123 @@
+package flexdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+type cursorToken struct {
+	Values []interface{} `json:"v"`
+	Id     float64       `json:"id"`
+}
+
+// Page cuts a sorted id list down to one page, next is the cursor of the following page if there is one
+func (db *Database) Page(tableName *string, sortedList []float64, keys []Order, offset int, limit int, cursor string) (pageList []float64, next string, err error) {
+	t, err := db.LoadTable(tableName)
+	if err != nil {
+		return
+	}
+
+	// resume after the row the cursor points at
+	start := 0
+	if cursor != "" {
+		token, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(token.Values) != len(keys) {
+			return nil, "", errors.New("cursor does not match query sort")
+		}
+		rows, err := t.sortRows(sortedList, keys)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(sortedList), func(i int) bool {
+			id := sortedList[i]
+			return compareRows(keys, rows[id], id, token.Values, token.Id) > 0
+		})
+	}
+
+	start += offset
+	if start > len(sortedList) {
+		start = len(sortedList)
+	}
+	end := len(sortedList)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+		last := sortedList[end-1]
+		rows, err := t.sortRows([]float64{last}, keys)
+		if err != nil {
+			return nil, "", err
+		}
+		next = encodeCursor(cursorToken{Values: rows[last], Id: last})
+	}
+	pageList = sortedList[start:end]
+
+	return
+}
+
+// pageSorted walks the index of the single sort key of q and stops after one page
+func (t *Table) pageSorted(q Query) (pageList []float64, next string, err error) {
+	var token *cursorToken
+	if q.Cursor != "" {
+		cursor, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(cursor.Values) != len(q.Sort) {
+			return nil, "", errors.New("cursor does not match query sort")
+		}
+		token = &cursor
+	}
+
+	key := q.Sort[0]
+	skip := q.Offset
+	walked := 0
+	pageList = []float64{}
+	var lastValue interface{}
+	err = t.walkSorted(key, func(id float64, value interface{}) bool {
+		walked++
+		// rows up to the cursor were on the previous pages
+		if token != nil && compareRows(q.Sort, []interface{}{value}, id, token.Values, token.Id) <= 0 {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if len(pageList) == q.Limit {
+			next = encodeCursor(cursorToken{Values: []interface{}{lastValue}, Id: pageList[len(pageList)-1]})
+			return false
+		}
+		pageList = append(pageList, id)
+		lastValue = value
+		return true
+	})
+	q.Stats.scan(walked)
+	q.Stats.sortIndexes(q.Sort)
+	if key.Field == "id" && key.Type == "float64" {
+		q.Stats.useIndex("id_float64")
+	}
+
+	return
+}
+
+func encodeCursor(token cursorToken) string {
+	data, _ := json.Marshal(token)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (token cursorToken, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &token)
+	}
+	if err != nil {
+		err = errors.New("cursor is invalid")
+	}
+
+	return
+}
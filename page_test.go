@@ -0,0 +1,113 @@
+package flexdb
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func newPageTable(t *testing.T) *Database {
+	t.Helper()
+	var docs []string
+	for i := 1; i <= 7; i++ {
+		docs = append(docs, fmt.Sprintf(`{"id":%d,"rank":%d}`, i, i%3))
+	}
+
+	return newTestTable(t, "items", docs...)
+}
+
+func docIds(t *testing.T, result interface{}) (ids []float64) {
+	t.Helper()
+	ids = []float64{}
+	for _, doc := range result.([]Doc) {
+		id, err := doc.GetId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	return
+}
+
+func TestPageOffset(t *testing.T) {
+	db := newPageTable(t)
+	rankDesc := []Order{{Field: "rank", Type: "float64", Direction: "desc"}}
+	where := []Where{{Field: "id", Operator: ">", Value: 0.0}}
+	tests := []struct {
+		name   string
+		query  Query
+		want   []float64
+		next   bool
+		hasErr bool
+	}{
+		{"all first page", Query{Type: "all", Limit: 3}, []float64{1, 2, 3}, true, false},
+		{"all offset", Query{Type: "all", Limit: 3, Offset: 5}, []float64{6, 7}, false, false},
+		{"all offset past end", Query{Type: "all", Limit: 3, Offset: 10}, []float64{}, false, false},
+		{"mget sorted", Query{Type: "mget", Where: where, Sort: rankDesc, Limit: 4}, []float64{2, 5, 1, 4}, true, false},
+		{"mget sorted offset", Query{Type: "mget", Where: where, Sort: rankDesc, Offset: 4}, []float64{7, 3, 6}, false, false},
+		{"negative offset", Query{Type: "mget", Where: where, Offset: -2}, nil, false, true},
+		{"all negative offset", Query{Type: "all", Offset: -2}, nil, false, true},
+		{"negative limit", Query{Type: "all", Limit: -1}, nil, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := test.query
+			q.Table = "items"
+			result, err := db.Run(&q)
+			if test.hasErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := docIds(t, result); !reflect.DeepEqual(ids, test.want) {
+				t.Fatalf("ids = %v, want %v", ids, test.want)
+			}
+			if (q.Next != "") != test.next {
+				t.Fatalf("next = %q, want next %v", q.Next, test.next)
+			}
+		})
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	db := newPageTable(t)
+	for _, queryType := range []string{"all", "mget"} {
+		t.Run(queryType, func(t *testing.T) {
+			q := Query{
+				Table: "items",
+				Type:  queryType,
+				Sort:  []Order{{Field: "rank", Type: "float64"}},
+				Limit: 3,
+			}
+			if queryType == "mget" {
+				q.Where = []Where{{Field: "id", Operator: ">", Value: 0.0}}
+			}
+			var ids []float64
+			for page := 0; page < 5; page++ {
+				result, err := db.Run(&q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, docIds(t, result)...)
+				if q.Next == "" {
+					break
+				}
+				q.Cursor = q.Next
+			}
+			want := []float64{3, 6, 1, 4, 7, 2, 5}
+			if !reflect.DeepEqual(ids, want) {
+				t.Fatalf("ids = %v, want %v", ids, want)
+			}
+		})
+	}
+
+	_, err := db.Run(&Query{Table: "items", Type: "all", Cursor: "not a cursor"})
+	if err == nil {
+		t.Fatal("expected an invalid cursor error")
+	}
+}
@@ -0,0 +1,246 @@
+package flexdb
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// plan methods
+const (
+	PlanIndex = "index" // ids come from the sorted index
+	PlanScan  = "scan"  // every doc of the table is checked
+	PlanCheck = "check" // only the candidates of the previous filters are checked
+	PlanMerge = "merge" // ids come from the child plans
+)
+
+// Plan is how a filter runs, the explain query type returns it without running it
+type Plan struct {
+	Type     string `json:"type,omitempty"` // and, or, not for filter groups
+	Where    *Where `json:"where,omitempty"`
+	Method   string `json:"method"`
+	Estimate int    `json:"estimate"` // estimated number of matching docs
+	Filters  []Plan `json:"filters,omitempty"`
+
+	checkable bool     // Where can be checked on a doc value
+	numbers   bool     // checked like a scan without an index
+	indexes   []string // index keys Where reads
+}
+
+func (db *Database) ExplainQuery(q Query) (result interface{}, err error) {
+	t, err := db.LoadTable(&q.Table)
+	if err != nil {
+		return
+	}
+	if !q.HasFilter() {
+		return Plan{Method: PlanScan, Estimate: t.count()}, nil
+	}
+
+	return t.plan(q.filter(), q.IndexMode)
+}
+
+// plan estimates every filter from index sizes and orders the children of and
+// so the smallest candidate set is found first
+func (t *Table) plan(filter *Filter, indexMode string) (plan Plan, err error) {
+	if filter.Where != nil {
+		// a leaf with a type would silently drop it
+		if filter.Type != "" || len(filter.Filters) != 0 {
+			return plan, errors.New("filter should hold either where or type and filters")
+		}
+		return t.planWhere(filter.Where, indexMode)
+	}
+
+	plan = Plan{Type: filter.Type, Method: PlanMerge}
+	for i := range filter.Filters {
+		child, err := t.plan(&filter.Filters[i], indexMode)
+		if err != nil {
+			return plan, err
+		}
+		plan.Filters = append(plan.Filters, child)
+	}
+
+	switch filter.Type {
+	case "", "and":
+		sort.SliceStable(plan.Filters, func(i, j int) bool {
+			return plan.Filters[i].Estimate < plan.Filters[j].Estimate
+		})
+		for i := range plan.Filters {
+			child := &plan.Filters[i]
+			// checking a few candidates beats reading a bigger id list
+			if i > 0 && child.checkable && plan.Filters[0].Estimate < child.Estimate {
+				child.Method = PlanCheck
+			}
+		}
+		if len(plan.Filters) > 0 {
+			plan.Estimate = plan.Filters[0].Estimate
+		}
+	case "or":
+		for _, child := range plan.Filters {
+			plan.Estimate += child.Estimate
+		}
+		if count := t.count(); plan.Estimate > count {
+			plan.Estimate = count
+		}
+	case "not":
+		if len(plan.Filters) != 1 {
+			return plan, errors.New("not filter should have exactly one filter")
+		}
+		plan.Estimate = t.count() - plan.Filters[0].Estimate
+		if plan.Estimate < 0 {
+			plan.Estimate = 0
+		}
+	default:
+		err = errors.New("filter type is unknown: " + filter.Type)
+	}
+
+	return
+}
+
+func (t *Table) planWhere(where *Where, indexMode string) (plan Plan, err error) {
+	err = checkWhere(*where)
+	if err != nil {
+		return
+	}
+	plan = Plan{Where: where, Method: PlanIndex}
+
+	switch where.Operator {
+	case "in":
+		for _, value := range where.Value.([]interface{}) {
+			valuePlan, err := t.planWhere(&Where{Field: where.Field, Value: value, IgnoreCase: where.IgnoreCase}, indexMode)
+			if err != nil {
+				return plan, err
+			}
+			plan.Estimate += valuePlan.Estimate
+			plan.indexes = append(plan.indexes, valuePlan.indexes...)
+		}
+		return
+	case "nin":
+		for _, valueType := range indexTypes {
+			if ti, ok := t.Engine.LoadIndex(where.Field + "_" + valueType); ok {
+				plan.Estimate += len(*ti)
+				plan.indexes = append(plan.indexes, where.Field+"_"+valueType)
+			}
+		}
+		return
+	case "contains", "regex":
+		plan.Method = PlanScan
+		plan.Estimate = t.count()
+		plan.checkable = true
+		return
+	}
+
+	valueType := reflect.TypeOf(where.Value).String()
+	ti, ok := t.Engine.LoadIndex(where.Field + "_" + valueType)
+	if !ok {
+		switch indexMode {
+		case IndexModeScan:
+			plan.Method = PlanScan
+			plan.Estimate = t.count()
+			plan.checkable = true
+			plan.numbers = true
+		case IndexModeStrict:
+			err = errors.New("no index for field " + where.Field + " of type " + valueType)
+		}
+		return
+	}
+
+	plan.indexes = []string{where.Field + "_" + valueType}
+	index := *ti
+	value := where.Value
+	if text, ok := value.(string); ok {
+		if t.isCaseSensitive(where.Field) && where.IgnoreCase {
+			// foldIds walks the whole index
+			plan.Estimate = len(index)
+			return
+		}
+		if !t.isCaseSensitive(where.Field) {
+			value = strings.ToLower(text)
+		}
+	}
+	plan.checkable = true
+	ranges, err := indexRanges(index, where.Operator, value)
+	if err != nil {
+		return
+	}
+	for _, positions := range ranges {
+		plan.Estimate += positions[1] - positions[0]
+	}
+
+	return
+}
+
+// runPlan finds the ids of a plan, an and stops as soon as no candidate is left
+func (t *Table) runPlan(plan Plan, indexMode string, stats *Stats) (ids []float64, err error) {
+	if plan.Where != nil {
+		// the estimate of a where is the number of items its index or scan reads
+		stats.scan(plan.Estimate)
+		for _, indexKey := range plan.indexes {
+			stats.useIndex(indexKey)
+		}
+		return t.whereIds(*plan.Where, indexMode)
+	}
+
+	if plan.Type == "" || plan.Type == "and" {
+		for i, child := range plan.Filters {
+			if i > 0 && len(ids) == 0 {
+				break
+			}
+			if child.Method == PlanCheck {
+				stats.scan(len(ids))
+				ids, err = t.checkIds(ids, child)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			childIds, err := t.runPlan(child, indexMode, stats)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				ids = childIds
+			} else {
+				ids = and([][]float64{ids, childIds})
+			}
+		}
+		return
+	}
+
+	var lists [][]float64
+	for _, child := range plan.Filters {
+		childIds, err := t.runPlan(child, indexMode, stats)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, childIds)
+	}
+	if plan.Type == "or" {
+		return or(lists), nil
+	}
+
+	stats.scan(t.count())
+	return t.allIds(lists[0]), nil
+}
+
+// checkIds keeps the candidates whose doc matches the where of plan
+func (t *Table) checkIds(candidates []float64, plan Plan) (ids []float64, err error) {
+	match, err := t.valueMatch(*plan.Where, plan.numbers)
+	if err != nil {
+		return
+	}
+	path := strings.Split(plan.Where.Field, ".")
+	ids = []float64{}
+	for _, id := range candidates {
+		doc, ok := t.Engine.Get(id)
+		if !ok {
+			continue
+		}
+		val, err := getVal(doc.Fields, path)
+		if err == nil && match(val) {
+			ids = append(ids, id)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,148 @@
+package flexdb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func newPlannerTable(t *testing.T) *Database {
+	t.Helper()
+	var docs []string
+	for i := 1; i <= 30; i++ {
+		docs = append(docs, fmt.Sprintf(`{"id":%d,"age":%d,"city":"%s","vip":%v}`, i, i%10, []string{"Paris", "Rome", "Porto"}[i%3], i%7 == 0))
+	}
+
+	return newTestTable(t, "people", docs...)
+}
+
+// TestPlanEstimate makes sure the estimate of an indexed where is what its lookup returns
+func TestPlanEstimate(t *testing.T) {
+	db := newPlannerTable(t)
+	table := "people"
+	tb, err := db.LoadTable(&table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wheres := []Where{
+		{Field: "age", Operator: "=", Value: 3.0},
+		{Field: "age", Operator: ">", Value: 3.0},
+		{Field: "age", Operator: ">=", Value: 3.0},
+		{Field: "age", Operator: "<", Value: 3.0},
+		{Field: "age", Operator: "<=", Value: 3.0},
+		{Field: "age", Operator: "!=", Value: 3.0},
+		{Field: "age", Operator: "=", Value: 42.0},
+		{Field: "city", Operator: "prefix", Value: "P"},
+		{Field: "city", Operator: "=", Value: "ROME"},
+		{Field: "vip", Operator: "=", Value: true},
+		{Field: "age", Operator: "in", Value: []interface{}{1.0, 2.0}},
+	}
+	for _, where := range wheres {
+		where := where
+		t.Run(fmt.Sprint(where.Field, where.Operator, where.Value), func(t *testing.T) {
+			plan, err := tb.planWhere(&where, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids, err := tb.whereIds(where, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Estimate != len(ids) {
+				t.Fatalf("estimate = %v, lookup = %v", plan.Estimate, len(ids))
+			}
+		})
+	}
+}
+
+func TestPlanOrder(t *testing.T) {
+	db := newPlannerTable(t)
+	filter := &Filter{Filters: []Filter{
+		{Where: &Where{Field: "age", Operator: ">=", Value: 1.0}},
+		{Where: &Where{Field: "vip", Operator: "=", Value: true}},
+		{Where: &Where{Field: "city", Operator: "contains", Value: "o"}},
+	}}
+	result, err := db.Run(&Query{Table: "people", Type: "explain", Filter: filter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := result.(Plan)
+	var methods []string
+	for _, child := range plan.Filters {
+		methods = append(methods, child.Where.Field+" "+child.Method)
+	}
+	want := []string{"vip index", "age check", "city check"}
+	if !reflect.DeepEqual(methods, want) {
+		t.Fatalf("plan = %v, want %v", methods, want)
+	}
+	if plan.Estimate != 4 {
+		t.Fatalf("estimate = %v, want 4", plan.Estimate)
+	}
+}
+
+// TestPlanResults compares planned filters with checking every doc by hand
+func TestPlanResults(t *testing.T) {
+	db := newPlannerTable(t)
+	tests := []struct {
+		name   string
+		filter Filter
+		match  func(id int) bool
+	}{
+		{
+			name: "and",
+			filter: Filter{Filters: []Filter{
+				{Where: &Where{Field: "age", Operator: "<", Value: 5.0}},
+				{Where: &Where{Field: "city", Operator: "=", Value: "paris"}},
+			}},
+			match: func(id int) bool { return id%10 < 5 && id%3 == 0 },
+		},
+		{
+			name: "and with or",
+			filter: Filter{Filters: []Filter{
+				{Where: &Where{Field: "age", Operator: "!=", Value: 0.0}},
+				{Type: "or", Filters: []Filter{
+					{Where: &Where{Field: "vip", Operator: "=", Value: true}},
+					{Where: &Where{Field: "city", Operator: "prefix", Value: "po"}},
+				}},
+			}},
+			match: func(id int) bool { return id%10 != 0 && (id%7 == 0 || id%3 == 2) },
+		},
+		{
+			name: "not",
+			filter: Filter{Type: "not", Filters: []Filter{
+				{Where: &Where{Field: "age", Operator: ">", Value: 2.0}},
+			}},
+			match: func(id int) bool { return id%10 <= 2 },
+		},
+		{
+			name: "empty first",
+			filter: Filter{Filters: []Filter{
+				{Where: &Where{Field: "age", Operator: "=", Value: 99.0}},
+				{Where: &Where{Field: "city", Operator: "regex", Value: "^R"}},
+			}},
+			match: func(id int) bool { return false },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := db.Filter(&[]string{"people"}[0], &test.filter, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Float64s(ids)
+			want := []float64{}
+			for id := 1; id <= 30; id++ {
+				if test.match(id) {
+					want = append(want, float64(id))
+				}
+			}
+			if len(ids) == 0 {
+				ids = []float64{}
+			}
+			if !reflect.DeepEqual(ids, want) {
+				t.Fatalf("ids = %v, want %v", ids, want)
+			}
+		})
+	}
+}
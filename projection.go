@@ -0,0 +1,107 @@
+package flexdb
+
+import (
+	"errors"
+	"strings"
+)
+
+// CheckFields makes sure Fields is either an include list or an exclude list of "-" prefixed paths
+func (q *Query) CheckFields() (err error) {
+	excluded := 0
+	for _, field := range q.Fields {
+		if strings.HasPrefix(field, "-") {
+			excluded++
+		}
+	}
+	if excluded != 0 && excluded != len(q.Fields) {
+		err = errors.New("fields should be all included or all excluded")
+	}
+
+	return
+}
+
+func projectDocs(docs []Doc, fields []string) []Doc {
+	if len(fields) == 0 {
+		return docs
+	}
+	for i := range docs {
+		docs[i] = project(docs[i], fields)
+	}
+
+	return docs
+}
+
+// project copies the requested paths of doc, stored docs are never modified
+func project(doc Doc, fields []string) Doc {
+	if len(fields) == 0 {
+		return doc
+	}
+
+	projected := NewDoc()
+	if strings.HasPrefix(fields[0], "-") {
+		projected.Fields = doc.Fields
+		for _, field := range fields {
+			projected.Fields = withoutPath(projected.Fields, strings.Split(field[1:], "."))
+		}
+		return *projected
+	}
+
+	requested := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		requested[field] = true
+	}
+	if id, ok := doc.Fields["id"]; ok {
+		projected.Fields["id"] = id
+	}
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+		// copied with its parent
+		if hasRequestedParent(requested, path) {
+			continue
+		}
+		val, err := getVal(doc.Fields, path)
+		if err != nil {
+			continue
+		}
+		setPath(projected.Fields, path, val)
+	}
+
+	return *projected
+}
+
+func hasRequestedParent(requested map[string]bool, path []string) bool {
+	for i := 1; i < len(path); i++ {
+		if requested[strings.Join(path[:i], ".")] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutPath returns fields without path, copying only the maps on the way
+func withoutPath(fields map[string]interface{}, path []string) map[string]interface{} {
+	val, ok := fields[path[0]]
+	if !ok {
+		return fields
+	}
+	var child map[string]interface{}
+	if len(path) > 1 {
+		child, ok = val.(map[string]interface{})
+		if !ok {
+			return fields
+		}
+	}
+
+	result := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		result[key] = value
+	}
+	if len(path) == 1 {
+		delete(result, path[0])
+	} else {
+		result[path[0]] = withoutPath(child, path[1:])
+	}
+
+	return result
+}
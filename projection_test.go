@@ -0,0 +1,40 @@
+package flexdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	doc := Doc{Fields: map[string]interface{}{
+		"id":      1.0,
+		"name":    "Ann",
+		"address": map[string]interface{}{"city": "Paris", "zip": "75001"},
+		"office":  nil,
+	}}
+	tests := []struct {
+		name   string
+		fields []string
+		want   map[string]interface{}
+	}{
+		{"include", []string{"name"}, map[string]interface{}{"id": 1.0, "name": "Ann"}},
+		{"nested", []string{"address.city"}, map[string]interface{}{"id": 1.0, "address": map[string]interface{}{"city": "Paris"}}},
+		{"child then parent", []string{"address.city", "address"}, map[string]interface{}{"id": 1.0, "address": map[string]interface{}{"city": "Paris", "zip": "75001"}}},
+		{"parent then child", []string{"address", "address.city"}, map[string]interface{}{"id": 1.0, "address": map[string]interface{}{"city": "Paris", "zip": "75001"}}},
+		{"null parent", []string{"office.city"}, map[string]interface{}{"id": 1.0}},
+		{"exclude", []string{"-address.zip", "-office"}, map[string]interface{}{"id": 1.0, "name": "Ann", "address": map[string]interface{}{"city": "Paris"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projected := project(doc, test.fields)
+			if !reflect.DeepEqual(projected.Fields, test.want) {
+				t.Fatalf("project = %v, want %v", projected.Fields, test.want)
+			}
+		})
+	}
+
+	// stored doc is never modified
+	if _, ok := doc.Fields["address"].(map[string]interface{})["zip"]; !ok {
+		t.Fatal("project modified the stored doc")
+	}
+}
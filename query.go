@@ -5,27 +5,47 @@ import (
 	"time"
 )
 
+// index modes, by default a where without an index of its value type matches nothing
+const (
+	IndexModeScan   = "scan"   // check every doc instead
+	IndexModeStrict = "strict" // fail the query
+)
+
 type Query struct {
 	Table     string        `json:"table"`
 	QId       string        `json:"id"`
 	Doc       *Doc          `json:"doc"`
 	Where     []Where       `json:"where"`
 	WhereType string        `json:"where_type"`
+	Filter    *Filter       `json:"filter"`
 	Order     Order         `json:"order"`
+	Sort      []Order       `json:"sort"`
 	Limit     int           `json:"limit"`
+	Offset    int           `json:"offset"`
+	Cursor    string        `json:"cursor"` // next of the previous page
+	Next      string        `json:"next"`   // set by Run when more results follow
+	Fields    []string      `json:"fields"` // paths to return, or paths to drop when prefixed with "-"
+	Aggregate []Aggregate   `json:"aggregate"`
+	Group     *Group        `json:"group"`
+	Lookup    []Lookup      `json:"lookup"`     // docs of other tables to embed in every result doc
+	IndexMode string        `json:"index_mode"` // scan or strict, what to do when a where has no index
+	Distinct  *Distinct     `json:"distinct"`
 	Type      string        `json:"type"`
-	Took      time.Duration `json:"took"`
+	Took      time.Duration `json:"took"`  // set by Run
+	Stats     *Stats        `json:"stats"` // set by Run
 }
 
 type Where struct {
-	Field    string      `json:"field"`
-	Operator string      `json:"operator"`
-	Value    interface{} `json:"value"`
+	Field      string      `json:"field"`
+	Operator   string      `json:"operator"`
+	Value      interface{} `json:"value"`
+	IgnoreCase bool        `json:"ignore_case"` // only needed for case sensitive fields
 }
 
 type Order struct {
-	Field string `json:"field"`
-	Type  string `json:"type"`
+	Field     string `json:"field"`
+	Type      string `json:"type"`
+	Direction string `json:"direction"` // asc or desc, asc when empty
 }
 
 func (q *Query) Check() (err error) {
@@ -37,12 +57,49 @@ func (q *Query) Check() (err error) {
 	if err != nil {
 		return
 	}
+	err = q.CheckFields()
+	if err != nil {
+		return
+	}
+	err = q.CheckIndexMode()
+	if err != nil {
+		return
+	}
+	err = q.CheckLookup()
+	if err != nil {
+		return
+	}
+	err = q.CheckPage()
+	if err != nil {
+		return
+	}
 	q.CheckWhereType()
 	q.CheckOrder()
 
 	return
 }
 
+func (q *Query) CheckPage() (err error) {
+	if q.Offset < 0 {
+		return errors.New("query offset can not be negative")
+	}
+	if q.Limit < 0 {
+		return errors.New("query limit can not be negative")
+	}
+
+	return
+}
+
+func (q *Query) CheckIndexMode() (err error) {
+	switch q.IndexMode {
+	case "", IndexModeScan, IndexModeStrict:
+	default:
+		err = errors.New("index mode is unknown: " + q.IndexMode)
+	}
+
+	return
+}
+
 func (q *Query) CheckTable() (err error) {
 	if q.Table == "" {
 		err = errors.New("table name is empty")
@@ -71,4 +128,7 @@ func (q *Query) CheckOrder() {
 		q.Order.Field = "id"
 		q.Order.Type = "float64"
 	}
+	if len(q.Sort) == 0 {
+		q.Sort = []Order{q.Order}
+	}
 }
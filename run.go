@@ -1,5 +1,7 @@
 package flexdb
 
+import "time"
+
 func (db *Database) AddQuery(q Query) (result interface{}, err error) {
 	// add single doc
 	err = db.Add(&q.Table, q.Doc)
@@ -8,27 +10,36 @@ func (db *Database) AddQuery(q Query) (result interface{}, err error) {
 	return
 }
 
-func (db *Database) AllQuery(q Query) (result interface{}, err error) {
+func (db *Database) AllQuery(q Query) (result interface{}, next string, err error) {
 	// all docs need
 	var docs []Doc
-	err = db.All(q, &docs)
+	next, err = db.All(q, &docs)
 	if err != nil {
 		return
 	}
-	result = docs
+	docs, err = db.lookupDocs(docs, q.Lookup, q.Stats)
+	if err != nil {
+		return
+	}
+	result = projectDocs(docs, q.Fields)
 
 	return
 }
 
 func (db *Database) GetQuery(q Query) (result interface{}, err error) {
 	// where exist
-	if len(q.Where) != 0 {
-		filteredDocs, err := db.WhereQuery(q)
+	if q.HasFilter() {
+		filteredDocs, _, err := db.WhereQuery(q)
 		if err != nil {
 			return result, err
 		}
 		if len(filteredDocs) > 0 {
-			result = &filteredDocs[0]
+			filteredDocs, err = db.lookupDocs(filteredDocs[:1], q.Lookup, q.Stats)
+			if err != nil {
+				return nil, err
+			}
+			doc := project(filteredDocs[0], q.Fields)
+			result = &doc
 		}
 
 		//fmt.Println(">> 1, result, err", result, err)
@@ -43,6 +54,13 @@ func (db *Database) GetQuery(q Query) (result interface{}, err error) {
 			if err != nil {
 				return nil, err
 			}
+			q.Stats.fetch(1)
+			var docs []Doc
+			docs, err = db.lookupDocs([]Doc{*q.Doc}, q.Lookup, q.Stats)
+			if err != nil {
+				return nil, err
+			}
+			*q.Doc = project(docs[0], q.Fields)
 			result = q.Doc
 
 			//fmt.Println(">> 2, result, err", result, err)
@@ -63,25 +81,54 @@ func (db *Database) GetQuery(q Query) (result interface{}, err error) {
 	return
 }
 
-func (db *Database) MGetQuery(q Query) (result interface{}, err error) {
+func (db *Database) MGetQuery(q Query) (result interface{}, next string, err error) {
 	// where exist
-	if len(q.Where) != 0 {
-		filteredDocs, err := db.WhereQuery(q)
+	if q.HasFilter() {
+		filteredDocs, next, err := db.WhereQuery(q)
 		if err != nil {
-			return result, err
+			return result, "", err
+		}
+		filteredDocs, err = db.lookupDocs(filteredDocs, q.Lookup, q.Stats)
+		if err != nil {
+			return result, "", err
 		}
-		result = filteredDocs
+		result = projectDocs(filteredDocs, q.Fields)
 
-		return result, err
+		return result, next, err
 	}
 
 	// all docs need
 	var docs []Doc
-	err = db.All(q, &docs)
+	next, err = db.All(q, &docs)
 	if err != nil {
 		return
 	}
-	result = docs
+	docs, err = db.lookupDocs(docs, q.Lookup, q.Stats)
+	if err != nil {
+		return
+	}
+	result = projectDocs(docs, q.Fields)
+
+	return
+}
+
+func (db *Database) CountQuery(q Query) (result interface{}, err error) {
+	// where exist
+	if q.HasFilter() {
+		idList, err := db.Filter(&q.Table, q.filter(), q.IndexMode, q.Stats)
+		if err != nil {
+			return 0, err
+		}
+
+		return len(idList), nil
+	}
+
+	// whole table
+	t, err := db.LoadTable(&q.Table)
+	if err != nil {
+		return 0, err
+	}
+	result = t.count()
 
 	return
 }
@@ -89,16 +136,13 @@ func (db *Database) MGetQuery(q Query) (result interface{}, err error) {
 func (db *Database) ExistsQuery(q Query) (result interface{}, err error) {
 	result = false
 	// where exist
-	if len(q.Where) != 0 {
-		filteredDocs, err := db.WhereQuery(q)
+	if q.HasFilter() {
+		idList, err := db.Filter(&q.Table, q.filter(), q.IndexMode, q.Stats)
 		if err != nil {
 			return false, err
 		}
-		if len(filteredDocs) > 0 {
-			return true, nil
-		} else {
-			return false, nil
-		}
+
+		return len(idList) > 0, nil
 	}
 
 	// single doc
@@ -116,7 +160,7 @@ func (db *Database) ExistsQuery(q Query) (result interface{}, err error) {
 
 func (db *Database) ReplaceQuery(q Query) (result interface{}, err error) {
 	// single replace
-	if len(q.Where) == 0 {
+	if !q.HasFilter() {
 		err = db.Replace(&q.Table, q.Doc)
 		if err != nil {
 			return
@@ -127,7 +171,7 @@ func (db *Database) ReplaceQuery(q Query) (result interface{}, err error) {
 	}
 
 	// multiple - where exist
-	filteredDocs, err := db.WhereQuery(q)
+	filteredDocs, _, err := db.WhereQuery(q)
 	if err != nil {
 		return
 	}
@@ -149,16 +193,18 @@ func (db *Database) ReplaceQuery(q Query) (result interface{}, err error) {
 
 func (db *Database) UpdateQuery(q Query) (result interface{}, err error) {
 	// single update
-	if len(q.Where) == 0 {
+	if !q.HasFilter() {
 		err = db.Update(&q.Table, q.Doc)
 		if err != nil {
 			return
 		}
 		result = q.Doc
+
+		return
 	}
 
 	// multiple update - where
-	filteredDocs, err := db.WhereQuery(q)
+	filteredDocs, _, err := db.WhereQuery(q)
 	if err != nil {
 		return
 	}
@@ -180,7 +226,7 @@ func (db *Database) UpdateQuery(q Query) (result interface{}, err error) {
 
 func (db *Database) DeleteQuery(q Query) (result interface{}, err error) {
 	// single delete
-	if len(q.Where) == 0 {
+	if !q.HasFilter() {
 		err = db.Delete(&q.Table, q.Doc)
 		if err != nil {
 			return
@@ -191,7 +237,7 @@ func (db *Database) DeleteQuery(q Query) (result interface{}, err error) {
 	}
 
 	// multiple delete - where
-	filteredDocs, err := db.WhereQuery(q)
+	filteredDocs, _, err := db.WhereQuery(q)
 	if err != nil {
 		return
 	}
@@ -210,22 +256,33 @@ func (db *Database) DeleteQuery(q Query) (result interface{}, err error) {
 	return
 }
 
-func (db *Database) WhereQuery(q Query) (filteredDocs []Doc, err error) {
-	idList, err := db.Where(&q.Table, &q.Where, &q.WhereType)
+func (db *Database) WhereQuery(q Query) (filteredDocs []Doc, next string, err error) {
+	idList, err := db.Filter(&q.Table, q.filter(), q.IndexMode, q.Stats)
+	if err != nil {
+
+		return
+	}
+	start := time.Now()
+	q.Stats.sortIndexes(q.Sort)
+	sortedIdList, err := db.Sort(&q.Table, idList, q.Sort, 0)
 	if err != nil {
 
 		return
 	}
-	sortedIdList, err := db.Order(&q.Table, idList, &q.Order.Field, &q.Order.Type, &q.Limit)
+	pageIdList, next, err := db.Page(&q.Table, sortedIdList, q.Sort, q.Offset, q.Limit, q.Cursor)
 	if err != nil {
 
 		return
 	}
-	err = db.MultiGet(&q.Table, sortedIdList, &filteredDocs)
+	q.Stats.track(phaseOrder, start)
+	start = time.Now()
+	err = db.MultiGet(&q.Table, pageIdList, &filteredDocs)
 	if err != nil {
 
 		return
 	}
+	q.Stats.fetch(len(filteredDocs))
+	q.Stats.track(phaseMultiGet, start)
 
 	return
 }
@@ -0,0 +1,179 @@
+package flexdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const SnapshotVersion = 1
+
+type SnapshotHeader struct {
+	Version int `json:"version"`
+	Tables  int `json:"tables"`
+}
+
+type SnapshotTable struct {
+	Name          string          `json:"name"`
+	Docs          int             `json:"docs"`
+	Indexes       []SnapshotIndex `json:"indexes"`
+	CaseSensitive []string        `json:"case_sensitive,omitempty"`
+}
+
+type SnapshotIndex struct {
+	Field string `json:"field"`
+	Type  string `json:"type"`
+}
+
+func (db *Database) Snapshot(w io.Writer) (err error) {
+	var names []string
+	db.Tables.Range(func(key, value interface{}) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	err = enc.Encode(SnapshotHeader{
+		Version: SnapshotVersion,
+		Tables:  len(names),
+	})
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		t, err := db.LoadTable(&name)
+		if err != nil {
+			return err
+		}
+		docs, err := t.sortedDocs()
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(SnapshotTable{
+			Name:          name,
+			Docs:          len(docs),
+			Indexes:       t.indexMeta(),
+			CaseSensitive: t.caseSensitiveFields(),
+		})
+		if err != nil {
+			return err
+		}
+		for i := range docs {
+			err = enc.Encode(&docs[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return buf.Flush()
+}
+
+func LoadSnapshot(r io.Reader) (db *Database, err error) {
+	return loadSnapshot(r, nil)
+}
+
+// loadSnapshot puts the docs of tables listed in engines into their engine instead of memory
+func loadSnapshot(r io.Reader, engines map[string]StorageEngine) (db *Database, err error) {
+	db = NewDb()
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var header SnapshotHeader
+	err = dec.Decode(&header)
+	if err != nil {
+		return nil, errors.New("snapshot header is invalid: " + err.Error())
+	}
+	if header.Version != SnapshotVersion {
+		return nil, errors.New("snapshot version is not supported: " + strconv.Itoa(header.Version))
+	}
+	for i := 0; i < header.Tables; i++ {
+		var st SnapshotTable
+		err = dec.Decode(&st)
+		if err != nil {
+			return nil, err
+		}
+		t := NewTable()
+		if engine, ok := engines[st.Name]; ok {
+			t = &Table{Engine: engine}
+		}
+		for _, field := range st.CaseSensitive {
+			t.CaseSensitive.Store(field, true)
+		}
+		db.Tables.Store(st.Name, t)
+		for j := 0; j < st.Docs; j++ {
+			var doc Doc
+			err = dec.Decode(&doc)
+			if err != nil {
+				return nil, err
+			}
+			id, err := doc.GetId()
+			if err != nil {
+				return nil, err
+			}
+			err = t.Engine.Put(id, doc)
+			if err != nil {
+				return nil, err
+			}
+			err = db.addToIndex(&st.Name, &doc)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// keep indexes which had no items left
+		for _, index := range st.Indexes {
+			t.Engine.CreateIndex(index.Field + "_" + index.Type)
+		}
+	}
+
+	return
+}
+
+func (t *Table) sortedDocs() (docs []Doc, err error) {
+	err = t.Engine.Scan(func(id float64, doc Doc) bool {
+		docs = append(docs, doc)
+		return true
+	})
+	if err != nil {
+		return
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		first, _ := docs[i].GetId()
+		second, _ := docs[j].GetId()
+		return first < second
+	})
+
+	return
+}
+
+func (t *Table) indexMeta() (indexes []SnapshotIndex) {
+	t.Engine.IndexRange(func(indexKey string, index *[]IndexItem) bool {
+		sep := strings.LastIndex(indexKey, "_")
+		indexes = append(indexes, SnapshotIndex{
+			Field: indexKey[:sep],
+			Type:  indexKey[sep+1:],
+		})
+		return true
+	})
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Field+indexes[i].Type < indexes[j].Field+indexes[j].Type
+	})
+
+	return
+}
+
+func (t *Table) caseSensitiveFields() (fields []string) {
+	t.CaseSensitive.Range(func(key, value interface{}) bool {
+		fields = append(fields, key.(string))
+		return true
+	})
+	sort.Strings(fields)
+
+	return
+}
@@ -0,0 +1,99 @@
+package flexdb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":2,"name":"Bob","address":{"city":"Rome"}}`,
+		`{"id":1,"name":"Ann","age":30}`,
+	)
+	// a table whose indexes have no items left
+	empty := "empty"
+	if err := db.Add(&empty, &Doc{Fields: map[string]interface{}{"id": 1.0, "tag": "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	db.WaitIndexes()
+	if err := db.SetCaseSensitive("people", "name", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&empty, &Doc{Fields: map[string]interface{}{"id": 1.0}}); err != nil {
+		t.Fatal(err)
+	}
+	db.WaitIndexes()
+
+	var buf bytes.Buffer
+	if err := db.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"people", "empty"} {
+		want, _ := db.LoadTable(&table)
+		got, err := loaded.LoadTable(&table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(want.indexMeta()) == 0 {
+			t.Fatalf("%s has no indexes to compare", table)
+		}
+		if !reflect.DeepEqual(got.indexMeta(), want.indexMeta()) {
+			t.Fatalf("%s indexes = %v, want %v", table, got.indexMeta(), want.indexMeta())
+		}
+		if !reflect.DeepEqual(got.caseSensitiveFields(), want.caseSensitiveFields()) {
+			t.Fatalf("%s case sensitive = %v, want %v", table, got.caseSensitiveFields(), want.caseSensitiveFields())
+		}
+		gotDocs, _ := got.sortedDocs()
+		wantDocs, _ := want.sortedDocs()
+		if !reflect.DeepEqual(gotDocs, wantDocs) {
+			t.Fatalf("%s docs = %v, want %v", table, gotDocs, wantDocs)
+		}
+	}
+
+	// indexes are rebuilt with the case of the snapshot
+	result, err := loaded.Run(&Query{Table: "people", Type: "count", Where: []Where{{Field: "name", Value: "Bob"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 1 {
+		t.Fatalf("count = %v, want 1", result)
+	}
+
+	// a second snapshot is byte for byte the same
+	var again bytes.Buffer
+	if err := loaded.Snapshot(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != buf.String() {
+		t.Fatalf("snapshot after load = %q, want %q", again.String(), buf.String())
+	}
+}
+
+func TestLoadSnapshotInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"not json", "snapshot"},
+		{"newer version", `{"version":2,"tables":0}`},
+		{"missing table", `{"version":1,"tables":1}`},
+		{"missing doc", `{"version":1,"tables":1}` + "\n" + `{"name":"people","docs":1}`},
+		{"doc without id", `{"version":1,"tables":1}` + "\n" + `{"name":"people","docs":1}` + "\n" + `{"name":"ann"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadSnapshot(strings.NewReader(test.data))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
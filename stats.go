@@ -0,0 +1,70 @@
+package flexdb
+
+import "time"
+
+// Stats tells where a query spent its time, Run sets a new one on every query
+type Stats struct {
+	IndexesUsed []string      `json:"indexes_used"`
+	IdsScanned  int           `json:"ids_scanned"` // index items and docs looked at to find the matching ids
+	DocsFetched int           `json:"docs_fetched"`
+	Where       time.Duration `json:"where"`
+	Order       time.Duration `json:"order"`
+	MultiGet    time.Duration `json:"multiget"`
+}
+
+// stats phases
+const (
+	phaseWhere    = "where"
+	phaseOrder    = "order"
+	phaseMultiGet = "multiget"
+)
+
+// the methods do nothing on a nil Stats, so callers never check
+
+func (s *Stats) useIndex(indexKey string) {
+	if s == nil {
+		return
+	}
+	for _, key := range s.IndexesUsed {
+		if key == indexKey {
+			return
+		}
+	}
+	s.IndexesUsed = append(s.IndexesUsed, indexKey)
+}
+
+func (s *Stats) scan(count int) {
+	if s != nil {
+		s.IdsScanned += count
+	}
+}
+
+func (s *Stats) fetch(count int) {
+	if s != nil {
+		s.DocsFetched += count
+	}
+}
+
+func (s *Stats) track(phase string, start time.Time) {
+	if s == nil {
+		return
+	}
+	took := time.Since(start)
+	switch phase {
+	case phaseWhere:
+		s.Where += took
+	case phaseOrder:
+		s.Order += took
+	case phaseMultiGet:
+		s.MultiGet += took
+	}
+}
+
+// sortIndexes records the indexes an order reads, ids sort without one
+func (s *Stats) sortIndexes(keys []Order) {
+	for _, key := range keys {
+		if key.Field != "id" || key.Type != "float64" {
+			s.useIndex(key.Field + "_" + key.Type)
+		}
+	}
+}
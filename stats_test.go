@@ -0,0 +1,59 @@
+package flexdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunStats(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":1,"city":"paris","age":20}`,
+		`{"id":2,"city":"rome","age":30}`,
+		`{"id":3,"city":"paris","age":40}`,
+		`{"id":4,"city":"paris","age":50}`,
+	)
+	q := Query{
+		Table: "people",
+		Type:  "mget",
+		Where: []Where{{Field: "city", Value: "paris"}, {Field: "age", Operator: ">", Value: 25.0}},
+		Sort:  []Order{{Field: "age", Type: "float64", Direction: "desc"}},
+		Limit: 1,
+	}
+	result, err := db.Run(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := docIds(t, result); !reflect.DeepEqual(got, []float64{4}) {
+		t.Fatalf("ids = %v, want [4]", got)
+	}
+
+	stats := q.Stats
+	if stats == nil {
+		t.Fatal("stats are not set")
+	}
+	if want := []string{"city_string", "age_float64"}; !reflect.DeepEqual(stats.IndexesUsed, want) {
+		t.Fatalf("indexes used = %v, want %v", stats.IndexesUsed, want)
+	}
+	// three paris items and three ages above 25
+	if stats.IdsScanned != 6 {
+		t.Fatalf("ids scanned = %d, want 6", stats.IdsScanned)
+	}
+	if stats.DocsFetched != 1 {
+		t.Fatalf("docs fetched = %d, want 1", stats.DocsFetched)
+	}
+	if stats.Where <= 0 || stats.Order <= 0 || stats.MultiGet <= 0 {
+		t.Fatalf("phase times = %v, %v, %v, want all set", stats.Where, stats.Order, stats.MultiGet)
+	}
+	if q.Took < stats.Where+stats.Order+stats.MultiGet {
+		t.Fatalf("took = %v, below the phase times", q.Took)
+	}
+
+	// every run starts with new stats
+	q.Where = nil
+	if _, err := db.Run(&q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Stats == stats {
+		t.Fatalf("stats = %+v, want new stats", *q.Stats)
+	}
+}
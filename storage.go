@@ -0,0 +1,198 @@
+package flexdb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type StorageEngine interface {
+	Get(id float64) (doc Doc, ok bool)
+	Put(id float64, doc Doc) (err error)
+	Delete(id float64) (err error)
+	Scan(fn func(id float64, doc Doc) bool) (err error)
+
+	LoadIndex(key string) (index *[]IndexItem, ok bool)
+	CreateIndex(key string)
+	DropIndex(key string)
+	IndexPut(key string, item IndexItem)
+	IndexRemove(id float64)
+	IndexRange(fn func(key string, index *[]IndexItem) bool)
+}
+
+// indexStore keeps sorted indexes in memory, shared by all engines
+type indexStore struct {
+	indexes sync.Map //map[string]*[]IndexItem
+}
+
+func (s *indexStore) LoadIndex(key string) (index *[]IndexItem, ok bool) {
+	tableIndex, ok := s.indexes.Load(key)
+	if !ok {
+		return
+	}
+	index = tableIndex.(*[]IndexItem)
+
+	return
+}
+
+func (s *indexStore) CreateIndex(key string) {
+	s.indexes.LoadOrStore(key, &([]IndexItem{}))
+}
+
+func (s *indexStore) DropIndex(key string) {
+	s.indexes.Delete(key)
+}
+
+func (s *indexStore) IndexPut(key string, item IndexItem) {
+	tableIndex, _ := s.indexes.LoadOrStore(key, &([]IndexItem{}))
+	ti := tableIndex.(*[]IndexItem)
+	is := insertSorted(ti, &item)
+	s.indexes.Store(key, &is)
+}
+
+func (s *indexStore) IndexRemove(id float64) {
+	s.indexes.Range(func(key, tableIndex interface{}) bool {
+		ti := tableIndex.(*[]IndexItem)
+		for i := 0; i < len(*ti); i++ {
+			if (*ti)[i].Id == id {
+				s.indexes.Store(key, removeFromIndexSlice(*ti, i))
+				break
+			}
+		}
+		return true
+	})
+}
+
+func (s *indexStore) IndexRange(fn func(key string, index *[]IndexItem) bool) {
+	s.indexes.Range(func(key, tableIndex interface{}) bool {
+		return fn(key.(string), tableIndex.(*[]IndexItem))
+	})
+}
+
+type MemoryEngine struct {
+	docs sync.Map //map[float64]Doc
+	indexStore
+}
+
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{}
+}
+
+func (e *MemoryEngine) Get(id float64) (doc Doc, ok bool) {
+	value, ok := e.docs.Load(id)
+	if !ok {
+		return
+	}
+	doc = value.(Doc)
+
+	return
+}
+
+func (e *MemoryEngine) Put(id float64, doc Doc) (err error) {
+	e.docs.Store(id, doc)
+
+	return
+}
+
+func (e *MemoryEngine) Delete(id float64) (err error) {
+	e.docs.Delete(id)
+
+	return
+}
+
+func (e *MemoryEngine) Scan(fn func(id float64, doc Doc) bool) (err error) {
+	e.docs.Range(func(key, value interface{}) bool {
+		return fn(key.(float64), value.(Doc))
+	})
+
+	return
+}
+
+// FileEngine keeps every doc as a plain json file in Dir, indexes stay in memory,
+// it can not be used with an encrypted database
+type FileEngine struct {
+	Dir string
+	indexStore
+}
+
+func NewFileEngine(dir string) (e *FileEngine, err error) {
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return
+	}
+	e = &FileEngine{
+		Dir: dir,
+	}
+
+	return
+}
+
+func (e *FileEngine) docPath(id float64) string {
+	return filepath.Join(e.Dir, strconv.FormatFloat(id, 'f', -1, 64)+".json")
+}
+
+func (e *FileEngine) Get(id float64) (doc Doc, ok bool) {
+	data, err := ioutil.ReadFile(e.docPath(id))
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return
+	}
+
+	return doc, true
+}
+
+func (e *FileEngine) Put(id float64, doc Doc) (err error) {
+	data, err := json.Marshal(&doc)
+	if err != nil {
+		return
+	}
+	path := e.docPath(id)
+	err = ioutil.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+func (e *FileEngine) Delete(id float64) (err error) {
+	err = os.Remove(e.docPath(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return
+}
+
+func (e *FileEngine) Scan(fn func(id float64, doc Doc) bool) (err error) {
+	files, err := ioutil.ReadDir(e.Dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		id, err := strconv.ParseFloat(strings.TrimSuffix(name, ".json"), 64)
+		if err != nil {
+			continue
+		}
+		doc, ok := e.Get(id)
+		if !ok {
+			continue
+		}
+		if !fn(id, doc) {
+			break
+		}
+	}
+
+	return
+}
@@ -1,8 +1,110 @@
 package flexdb
 
-import "sync"
+import (
+	"errors"
+	"strings"
+	"sync"
+)
 
 type Table struct {
-	Docs    sync.Map //map[uint]Doc
-	Indexes sync.Map //map[string]Index
+	Engine        StorageEngine
+	CaseSensitive sync.Map //map[string]bool, string fields indexed with their original case
+}
+
+func NewTable() *Table {
+	return &Table{
+		Engine: NewMemoryEngine(),
+	}
+}
+
+// CreateTable keeps a new table in engine, Open takes the engines from Options.Engines
+func (db *Database) CreateTable(tableName string, engine StorageEngine) (err error) {
+	err = checkEngine(tableName, engine, db.crypter)
+	if err != nil {
+		return
+	}
+	t := &Table{
+		Engine: engine,
+	}
+	_, loaded := db.Tables.LoadOrStore(tableName, t)
+	if loaded {
+		return errors.New("table already exists: " + tableName)
+	}
+
+	// index docs already kept by engine
+	var indexErr error
+	err = engine.Scan(func(id float64, doc Doc) bool {
+		indexErr = db.addToIndex(&tableName, &doc)
+		return indexErr == nil
+	})
+	if err != nil {
+		return
+	}
+
+	return indexErr
+}
+
+// checkEngine refuses engines which would keep docs of an encrypted database as plain text
+func checkEngine(tableName string, engine StorageEngine, crypter *Crypter) (err error) {
+	if _, ok := engine.(*FileEngine); ok && crypter != nil {
+		return errors.New("file engine does not support encryption, table: " + tableName)
+	}
+
+	return
+}
+
+func (t *Table) isCaseSensitive(field string) bool {
+	_, ok := t.CaseSensitive.Load(field)
+
+	return ok
+}
+
+// SetCaseSensitive switches how a string field is indexed and matched, its index is rebuilt
+// while writers are blocked, so no doc gets indexed by both the rebuild and its own write
+func (db *Database) SetCaseSensitive(tableName string, field string, sensitive bool) (err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	err = db.logItem(BucketItem{
+		Table:         tableName,
+		Type:          2,
+		Field:         field,
+		CaseSensitive: sensitive,
+	})
+	if err != nil {
+		return
+	}
+
+	return db.setCaseSensitive(tableName, field, sensitive)
+}
+
+func (db *Database) setCaseSensitive(tableName string, field string, sensitive bool) (err error) {
+	table, _ := db.Tables.LoadOrStore(tableName, NewTable())
+	t := table.(*Table)
+	db.WaitIndexes()
+	if sensitive {
+		t.CaseSensitive.Store(field, true)
+	} else {
+		t.CaseSensitive.Delete(field)
+	}
+
+	indexKey := field + "_string"
+	path := strings.Split(field, ".")
+	t.Engine.DropIndex(indexKey)
+
+	return t.Engine.Scan(func(id float64, doc Doc) bool {
+		val, err := getVal(doc.Fields, path)
+		value, ok := val.(string)
+		if err != nil || !ok || value == "" {
+			return true
+		}
+		if !sensitive {
+			value = strings.ToLower(value)
+		}
+		t.Engine.IndexPut(indexKey, IndexItem{
+			Id:    id,
+			Value: value,
+		})
+		return true
+	})
 }
@@ -0,0 +1,243 @@
+package flexdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tempDir is a data directory for one test, remove it with os.RemoveAll
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "flexdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestSetCaseSensitiveSurvivesRestart(t *testing.T) {
+	for _, checkpoint := range []bool{false, true} {
+		name := "log"
+		if checkpoint {
+			name = "snapshot"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			db, err := Open(dir, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			table := "people"
+			for _, fields := range []map[string]interface{}{
+				{"id": 1.0, "code": "AB"},
+				{"id": 2.0, "code": "ab"},
+			} {
+				if err := db.Add(&table, &Doc{Fields: fields}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			db.WaitIndexes()
+			if err := db.SetCaseSensitive(table, "code", true); err != nil {
+				t.Fatal(err)
+			}
+			if checkpoint {
+				if err := db.Checkpoint(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			db, err = Open(dir, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			result, err := db.Run(&Query{Table: table, Type: "count", Where: []Where{{Field: "code", Value: "AB"}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != 1 {
+				t.Fatalf("count = %v, want 1 after restart", result)
+			}
+		})
+	}
+}
+
+func TestEngineSurvivesRestart(t *testing.T) {
+	for _, checkpoint := range []bool{false, true} {
+		name := "log"
+		if checkpoint {
+			name = "snapshot"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			table := "people"
+			open := func() *Database {
+				engine, err := NewFileEngine(filepath.Join(dir, table))
+				if err != nil {
+					t.Fatal(err)
+				}
+				db, err := Open(dir, Options{Engines: map[string]StorageEngine{table: engine}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return db
+			}
+
+			db := open()
+			for i, name := range []string{"ann", "bob", "cat"} {
+				if err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": float64(i + 1), "name": name}}); err != nil {
+					t.Fatal(err)
+				}
+				if i == 1 && checkpoint {
+					db.WaitIndexes()
+					if err := db.Checkpoint(); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			db.WaitIndexes()
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			db = open()
+			defer db.Close()
+			tb, err := db.LoadTable(&table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := tb.Engine.(*FileEngine); !ok {
+				t.Fatalf("engine = %T, want *FileEngine", tb.Engine)
+			}
+			for _, q := range []Query{
+				{Table: table, Type: "count"},
+				{Table: table, Type: "count", Where: []Where{{Field: "name", Operator: ">=", Value: "a"}}},
+			} {
+				result, err := db.Run(&q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result != 3 {
+					t.Fatalf("count where %v = %v, want 3 after restart", q.Where, result)
+				}
+			}
+		})
+	}
+}
+
+func TestFileEngineRejectsEncryption(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	key := make([]byte, 32)
+	engine, err := NewFileEngine(filepath.Join(dir, "files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Open(dir, Options{EncryptionKey: key, Engines: map[string]StorageEngine{"files": engine}})
+	if err == nil {
+		t.Fatal("Open with a file engine and a key should fail")
+	}
+	db, err := Open(dir, Options{EncryptionKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.CreateTable("files", engine); err == nil {
+		t.Fatal("CreateTable with a file engine should fail on an encrypted database")
+	}
+	if err := db.CreateTable("memory", NewMemoryEngine()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetCaseSensitiveDuringWrites(t *testing.T) {
+	db := NewDb()
+	table := "people"
+	done := make(chan error, 1)
+	go func() {
+		for i := 1; i <= 200; i++ {
+			err := db.Add(&table, &Doc{Fields: map[string]interface{}{"id": float64(i), "code": "AB"}})
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	// rebuild the index again and again while docs are added
+	sensitive := false
+	for finished := false; !finished; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			finished = true
+		default:
+			sensitive = !sensitive
+			if err := db.SetCaseSensitive(table, "code", sensitive); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	db.WaitIndexes()
+
+	tb, err := db.LoadTable(&table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti, ok := tb.Engine.LoadIndex("code_string")
+	if !ok {
+		t.Fatal("code index is missing")
+	}
+	if len(*ti) != 200 {
+		t.Fatalf("index items = %d, want one per doc", len(*ti))
+	}
+}
+
+func TestCreateTableIndexError(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	engine, err := NewFileEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "1.json"), []byte(`{"name":"ann"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewDb()
+	if err := db.CreateTable("people", engine); err == nil {
+		t.Fatal("CreateTable should fail on a doc without id")
+	}
+}
+
+func TestRotateKeyRejectsFileEngine(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	engine, err := NewFileEngine(filepath.Join(dir, "files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(dir, Options{Engines: map[string]StorageEngine{"files": engine}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := <-db.RotateKey(make([]byte, 32)); err == nil {
+		t.Fatal("RotateKey should fail with a file engine table")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "snapshot.db")); !os.IsNotExist(err) {
+		t.Fatal("a refused rotation should not write a snapshot")
+	}
+}
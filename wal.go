@@ -0,0 +1,236 @@
+package flexdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+type Wal struct {
+	Path  string
+	file  *os.File
+	size  int64
+	dirty bool
+	mu    sync.Mutex
+
+	crypter *Crypter
+}
+
+func OpenWal(path string) (w *Wal, err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return
+	}
+	w = &Wal{
+		Path: path,
+		file: file,
+		size: info.Size(),
+	}
+
+	return
+}
+
+func (w *Wal) Write(item BucketItem) (err error) {
+	return w.WriteRecord(&item)
+}
+
+func (w *Wal) WriteRecord(record interface{}) (err error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.crypter != nil {
+		line, err = w.crypter.Seal(line)
+		if err != nil {
+			return
+		}
+		line = []byte(base64.StdEncoding.EncodeToString(line))
+	}
+	line = append(line, '\n')
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	w.dirty = true
+
+	return
+}
+
+func (w *Wal) Sync() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.dirty {
+		return
+	}
+	err = w.file.Sync()
+	if err != nil {
+		return
+	}
+	w.dirty = false
+
+	return
+}
+
+func (w *Wal) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.size
+}
+
+func (w *Wal) Truncate() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err = w.file.Truncate(0)
+	if err != nil {
+		return
+	}
+	w.size = 0
+	w.dirty = false
+
+	return w.file.Sync()
+}
+
+func (w *Wal) Replay(apply func(item *BucketItem) error) (err error) {
+	return w.ReplayRecords(func(record []byte) error {
+		var item BucketItem
+		err := json.Unmarshal(record, &item)
+		if err != nil {
+			return err
+		}
+		return apply(&item)
+	})
+}
+
+func (w *Wal) ReplayRecords(apply func(record []byte) error) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err = w.file.Seek(0, 0)
+	if err != nil {
+		return
+	}
+	offset, torn, err := readRecords(w.file, w.crypter, apply)
+	if err != nil {
+		return
+	}
+
+	// last line was not fully written, drop it
+	if torn {
+		err = w.file.Truncate(offset)
+		w.size = offset
+	}
+
+	return
+}
+
+// readRecords returns the offset after the last complete line and whether a partial line follows it
+func readRecords(r io.Reader, crypter *Crypter, apply func(record []byte) error) (offset int64, torn bool, err error) {
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil {
+			if readErr != io.EOF {
+				return offset, false, readErr
+			}
+			return offset, len(line) > 0, nil
+		}
+		record := line
+		if crypter != nil {
+			record, err = openLine(crypter, line)
+			if err != nil {
+				return
+			}
+		}
+		err = apply(record)
+		if err != nil {
+			return
+		}
+		offset += int64(len(line))
+	}
+}
+
+func (w *Wal) SetCrypter(crypter *Crypter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.crypter = crypter
+}
+
+func openLine(crypter *Crypter, line []byte) (record []byte, err error) {
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(line)))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+
+	return crypter.Open(sealed)
+}
+
+// swap replaces the log file with the one at tempPath, its lines are sealed with crypter
+func (w *Wal) swap(tempPath string, crypter *Crypter) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err = os.Rename(tempPath, w.Path)
+	if err != nil {
+		return
+	}
+	_ = w.file.Close()
+	file, err := os.OpenFile(w.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return
+	}
+	w.file = file
+	w.size = info.Size()
+	w.dirty = false
+	w.crypter = crypter
+
+	return
+}
+
+// copyRecords writes the records of the log at path between offsets from and to into w,
+// opening them with crypter first, to below zero copies up to the end
+func copyRecords(path string, from int64, to int64, crypter *Crypter, w *Wal) (err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, err = file.Seek(from, 0)
+	if err != nil {
+		return
+	}
+	var r io.Reader = file
+	if to >= 0 {
+		r = io.LimitReader(file, to-from)
+	}
+	_, _, err = readRecords(r, crypter, func(record []byte) error {
+		return w.WriteRecord(json.RawMessage(record))
+	})
+
+	return
+}
+
+func (w *Wal) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
@@ -0,0 +1,155 @@
+package flexdb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// crash stops db the way a killed process would, nothing is synced or checkpointed
+func crash(db *Database) {
+	db.StopCheckpoint()
+	db.stopSyncer()
+	_ = db.Wal.Close()
+	if db.Journal != nil {
+		_ = db.Journal.Close()
+	}
+}
+
+func TestWalReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	db, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := "people"
+	for _, fields := range []map[string]interface{}{
+		{"id": 1.0, "name": "ann", "age": 30.0},
+		{"id": 2.0, "name": "bob", "age": 40.0},
+		{"id": 3.0, "name": "cat", "age": 50.0},
+	} {
+		if err := db.Add(&table, &Doc{Fields: fields}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Update(&table, &Doc{Fields: map[string]interface{}{"id": 2.0, "age": 45.0}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&table, &Doc{Fields: map[string]interface{}{"id": 3.0}}); err != nil {
+		t.Fatal(err)
+	}
+	db.WaitIndexes()
+	crash(db)
+
+	db, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tests := []struct {
+		name  string
+		where []Where
+		want  []float64
+	}{
+		{"all", nil, []float64{1, 2}},
+		{"updated value", []Where{{Field: "age", Value: 45.0}}, []float64{2}},
+		{"old value", []Where{{Field: "age", Value: 40.0}}, []float64{}},
+		{"deleted doc", []Where{{Field: "name", Value: "cat"}}, []float64{}},
+		{"range", []Where{{Field: "age", Operator: "<", Value: 50.0}}, []float64{1, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := db.Run(&Query{Table: table, Type: "mget", Where: test.where})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := docIds(t, result); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ids = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReplayRecordsTornLine(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string
+		torn bool
+	}{
+		{"complete", "", false},
+		{"half record", `{"table":"people","type":1,"doc":{"id":`, true},
+		{"missing newline", `{"table":"people","type":1,"doc":{"id":9}}`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "wal.log")
+			w, err := OpenWal(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 2; i++ {
+				err = w.Write(BucketItem{Table: "people", Type: 1, Doc: Doc{Fields: map[string]interface{}{"id": float64(i)}}})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			complete := w.Size()
+			_ = w.Close()
+
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = file.WriteString(test.tail)
+			_ = file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w, err = OpenWal(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			records := 0
+			err = w.ReplayRecords(func(record []byte) error {
+				records++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if records != 2 {
+				t.Fatalf("records = %d, want 2", records)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != complete || w.Size() != complete {
+				t.Fatalf("size = %d (wal %d), want %d after dropping the torn line", info.Size(), w.Size(), complete)
+			}
+
+			// new writes start on a clean line
+			err = w.Write(BucketItem{Table: "people", Type: 1, Doc: Doc{Fields: map[string]interface{}{"id": 3.0}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			records = 0
+			err = w.Replay(func(item *BucketItem) error {
+				records++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if records != 3 {
+				t.Fatalf("records = %d, want 3 after a new write", records)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package flexdb
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// indexTypes are the value types addToIndex keeps sorted indexes for
+var indexTypes = []string{"string", "float64", "bool"}
+
+// checkWhere catches values the operator can not work with
+func checkWhere(where Where) (err error) {
+	switch where.Operator {
+	case "in", "nin":
+		if _, ok := where.Value.([]interface{}); !ok {
+			return errors.New("where value should be a list for operator " + where.Operator + ": " + where.Field)
+		}
+		return
+	case "prefix", "contains", "regex":
+		if _, ok := where.Value.(string); !ok {
+			return errors.New("where value should be a string for operator " + where.Operator + ": " + where.Field)
+		}
+	}
+	if where.Value == nil {
+		return errors.New("where value is empty for field: " + where.Field)
+	}
+
+	return
+}
+
+func (t *Table) whereIds(where Where, indexMode string) (ids []float64, err error) {
+	err = checkWhere(where)
+	if err != nil {
+		return
+	}
+	switch where.Operator {
+	case "in", "nin":
+		return t.whereInIds(where, indexMode)
+	case "contains", "regex":
+		match, err := t.valueMatch(where, false)
+		if err != nil {
+			return nil, err
+		}
+		return t.scanIds(where.Field, match)
+	}
+	valueType := reflect.TypeOf(where.Value).String()
+	ti, ok := t.Engine.LoadIndex(where.Field + "_" + valueType)
+	if !ok {
+		switch indexMode {
+		case IndexModeScan:
+			match, err := t.valueMatch(where, true)
+			if err != nil {
+				return nil, err
+			}
+			return t.scanIds(where.Field, match)
+		case IndexModeStrict:
+			return nil, errors.New("no index for field " + where.Field + " of type " + valueType)
+		}
+		return []float64{}, nil
+	}
+
+	if value, ok := where.Value.(string); ok {
+		if !t.isCaseSensitive(where.Field) {
+			where.Value = strings.ToLower(value)
+		} else if where.IgnoreCase {
+			return foldIds(*ti, where)
+		}
+	}
+
+	ranges, err := indexRanges(*ti, where.Operator, where.Value)
+	if err != nil {
+		return
+	}
+	ids = []float64{}
+	for _, positions := range ranges {
+		ids = append(ids, indexIds(*ti, positions[0], positions[1])...)
+	}
+
+	return
+}
+
+// indexRanges are the positions of the index items matching operator and value,
+// the planner estimates from the same ranges the lookup reads
+func indexRanges(index []IndexItem, operator string, value interface{}) (ranges [][2]int, err error) {
+	switch operator {
+	case "", "=", "==":
+		ranges = [][2]int{{lowerBound(index, value), upperBound(index, value)}}
+	case ">":
+		ranges = [][2]int{{upperBound(index, value), len(index)}}
+	case ">=":
+		ranges = [][2]int{{lowerBound(index, value), len(index)}}
+	case "<":
+		ranges = [][2]int{{0, lowerBound(index, value)}}
+	case "<=":
+		ranges = [][2]int{{0, upperBound(index, value)}}
+	case "!=":
+		ranges = [][2]int{{0, lowerBound(index, value)}, {upperBound(index, value), len(index)}}
+	case "prefix":
+		prefix := value.(string)
+		end := sort.Search(len(index), func(i int) bool {
+			value := index[i].Value.(string)
+			return value >= prefix && !strings.HasPrefix(value, prefix)
+		})
+		ranges = [][2]int{{lowerBound(index, prefix), end}}
+	default:
+		err = errors.New("where operator is unknown: " + operator)
+	}
+
+	return
+}
+
+func (t *Table) whereInIds(where Where, indexMode string) (ids []float64, err error) {
+	// one lookup per value
+	var lists [][]float64
+	for _, value := range where.Value.([]interface{}) {
+		valueIds, err := t.whereIds(Where{Field: where.Field, Value: value, IgnoreCase: where.IgnoreCase}, indexMode)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, valueIds)
+	}
+	ids = or(lists)
+	if where.Operator == "in" {
+		return
+	}
+
+	// nin keeps every doc holding the field with another value
+	found := make(map[float64]bool, len(ids))
+	for _, id := range ids {
+		found[id] = true
+	}
+	ids = []float64{}
+	for _, valueType := range indexTypes {
+		ti, ok := t.Engine.LoadIndex(where.Field + "_" + valueType)
+		if !ok {
+			continue
+		}
+		for _, item := range *ti {
+			if !found[item.Id] {
+				ids = append(ids, item.Id)
+			}
+		}
+	}
+
+	return
+}
+
+// foldIds matches a case sensitive index ignoring case, it walks the whole index
+func foldIds(index []IndexItem, where Where) (ids []float64, err error) {
+	needle := where.Value.(string)
+	var match func(value string) bool
+	switch where.Operator {
+	case "", "=", "==":
+		match = func(value string) bool {
+			return strings.EqualFold(value, needle)
+		}
+	case "!=":
+		match = func(value string) bool {
+			return !strings.EqualFold(value, needle)
+		}
+	case "prefix":
+		needle = strings.ToLower(needle)
+		match = func(value string) bool {
+			return strings.HasPrefix(strings.ToLower(value), needle)
+		}
+	default:
+		return nil, errors.New("ignore case is not supported for operator: " + where.Operator)
+	}
+
+	ids = []float64{}
+	for _, item := range index {
+		if match(item.Value.(string)) {
+			ids = append(ids, item.Id)
+		}
+	}
+
+	return
+}
+
+// valueMatch checks one doc value the way whereIds would match it,
+// numbers lets go ints match float64 values for scans without an index
+func (t *Table) valueMatch(where Where, numbers bool) (match func(val interface{}) bool, err error) {
+	operator := where.Operator
+	switch operator {
+	case "", "=":
+		operator = "=="
+	case "==", ">", ">=", "<", "<=", "!=", "prefix", "contains":
+	case "regex":
+		// fold like contains and prefix do
+		pattern := where.Value.(string)
+		if !t.isCaseSensitive(where.Field) || where.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(val interface{}) bool {
+			value, ok := val.(string)
+			return ok && re.MatchString(value)
+		}, nil
+	default:
+		return nil, errors.New("where operator is unknown: " + where.Operator)
+	}
+
+	if number, ok := toFloat64(where.Value); ok && numbers {
+		where.Value = number
+	}
+	fold := false
+	if value, ok := where.Value.(string); ok && (!t.isCaseSensitive(where.Field) || where.IgnoreCase) {
+		fold = true
+		where.Value = strings.ToLower(value)
+	}
+
+	return func(val interface{}) bool {
+		if value, ok := val.(string); ok {
+			// empty strings are never indexed, keep scan results the same
+			if value == "" && operator != "contains" {
+				return false
+			}
+			if fold {
+				val = strings.ToLower(value)
+			}
+		}
+		if number, ok := toFloat64(val); ok && numbers {
+			val = number
+		}
+		switch operator {
+		case "prefix", "contains":
+			value, ok := val.(string)
+			if operator == "prefix" {
+				return ok && strings.HasPrefix(value, where.Value.(string))
+			}
+			return ok && strings.Contains(value, where.Value.(string))
+		}
+		return compareInterface(val, operator, where.Value)
+	}, nil
+}
+
+// toFloat64 converts any go number, docs built in code may hold ints the index never sees
+func toFloat64(val interface{}) (float64, bool) {
+	value := reflect.ValueOf(val)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+
+	return 0, false
+}
+
+// scanIds checks the field of every doc, for conditions an index can not answer
+func (t *Table) scanIds(field string, match func(val interface{}) bool) (ids []float64, err error) {
+	path := strings.Split(field, ".")
+	ids = []float64{}
+	err = t.Engine.Scan(func(id float64, doc Doc) bool {
+		val, err := getVal(doc.Fields, path)
+		if err == nil && match(val) {
+			ids = append(ids, id)
+		}
+		return true
+	})
+
+	return
+}
+
+// lowerBound is the position of the first item not less than value
+func lowerBound(index []IndexItem, value interface{}) int {
+	return sort.Search(len(index), func(i int) bool {
+		return !compareInterface(index[i].Value, "<", value)
+	})
+}
+
+// upperBound is the position of the first item greater than value
+func upperBound(index []IndexItem, value interface{}) int {
+	return sort.Search(len(index), func(i int) bool {
+		return compareInterface(index[i].Value, ">", value)
+	})
+}
+
+func indexIds(index []IndexItem, from int, to int) (ids []float64) {
+	ids = make([]float64, 0, to-from)
+	for i := from; i < to; i++ {
+		ids = append(ids, index[i].Id)
+	}
+
+	return
+}
@@ -0,0 +1,275 @@
+package flexdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// newTestTable adds the json docs to table and waits for the indexes
+func newTestTable(t *testing.T, table string, docs ...string) *Database {
+	t.Helper()
+	db := NewDb()
+	for _, data := range docs {
+		doc := NewDoc()
+		if err := json.Unmarshal([]byte(data), doc); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Add(&table, doc); err != nil {
+			t.Fatal(err)
+		}
+		db.WaitIndexes()
+	}
+
+	return db
+}
+
+func TestGetValNullParent(t *testing.T) {
+	fields := map[string]interface{}{"address": nil}
+	if _, err := getVal(fields, []string{"address", "city"}); err == nil {
+		t.Fatal("expected path not found through a null parent")
+	}
+	if _, err := getVal(nil, []string{"city"}); err == nil {
+		t.Fatal("expected path not found on a nil input")
+	}
+}
+
+func TestScanNullParent(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":1,"address":null}`,
+		`{"id":2,"address":{"city":"Paris"}}`,
+		`{"id":3,"address":{"city":"Rome"}}`,
+	)
+	tests := []struct {
+		name  string
+		where Where
+		want  int
+	}{
+		{"contains", Where{Field: "address.city", Operator: "contains", Value: "ar"}, 1},
+		{"regex", Where{Field: "address.city", Operator: "regex", Value: "^R"}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := db.Run(&Query{Table: "people", Type: "count", Where: []Where{test.where}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.want {
+				t.Fatalf("count = %v, want %v", result, test.want)
+			}
+		})
+	}
+}
+
+func TestIndexMode(t *testing.T) {
+	db := NewDb()
+	table := "stock"
+	// ints set in code never reach a float64 index
+	for _, fields := range []map[string]interface{}{
+		{"id": 1.0, "stats": nil},
+		{"id": 2.0, "stats": map[string]interface{}{"qty": 3}},
+		{"id": 3.0, "stats": map[string]interface{}{"qty": 7}},
+	} {
+		if err := db.Add(&table, &Doc{Fields: fields}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.WaitIndexes()
+
+	where := []Where{{Field: "stats.qty", Operator: ">", Value: 2.0}}
+	tests := []struct {
+		mode    string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{IndexModeScan, 2, false},
+		{IndexModeStrict, 0, true},
+	}
+	for _, test := range tests {
+		t.Run("mode "+test.mode, func(t *testing.T) {
+			result, err := db.Run(&Query{Table: table, Type: "count", Where: where, IndexMode: test.mode})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.want {
+				t.Fatalf("count = %v, want %v", result, test.want)
+			}
+		})
+	}
+}
+
+func TestIndexModeScanCheck(t *testing.T) {
+	db := NewDb()
+	table := "stock"
+	for _, fields := range []map[string]interface{}{
+		{"id": 1.0, "stats": nil},
+		{"id": 2.0, "stats": map[string]interface{}{"qty": 3}},
+		{"id": 3.0, "stats": map[string]interface{}{"qty": 7}},
+	} {
+		if err := db.Add(&table, &Doc{Fields: fields}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.WaitIndexes()
+
+	// the id where is smaller, so the scan where only checks its candidates
+	q := Query{Table: table, Type: "count", IndexMode: IndexModeScan, Where: []Where{
+		{Field: "stats.qty", Operator: ">", Value: 2.0},
+		{Field: "id", Operator: "<=", Value: 2.0},
+	}}
+	plan, err := db.Run(&Query{Table: table, Type: "explain", IndexMode: q.IndexMode, Where: q.Where})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method := plan.(Plan).Filters[1].Method; method != PlanCheck {
+		t.Fatalf("scan where method = %v, want %v", method, PlanCheck)
+	}
+	result, err := db.Run(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 1 {
+		t.Fatalf("count = %v, want 1", result)
+	}
+}
+
+func TestWhereInIgnoreCase(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":1,"code":"AB"}`,
+		`{"id":2,"code":"ab"}`,
+		`{"id":3,"code":"Cd"}`,
+	)
+	if err := db.SetCaseSensitive("people", "code", true); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		where Where
+		want  int
+	}{
+		{"in", Where{Field: "code", Operator: "in", Value: []interface{}{"ab", "cd"}}, 1},
+		{"in ignore case", Where{Field: "code", Operator: "in", Value: []interface{}{"ab", "cd"}, IgnoreCase: true}, 3},
+		{"nin", Where{Field: "code", Operator: "nin", Value: []interface{}{"ab"}}, 2},
+		{"nin ignore case", Where{Field: "code", Operator: "nin", Value: []interface{}{"ab"}, IgnoreCase: true}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := db.Run(&Query{Table: "people", Type: "count", Where: []Where{test.where}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.want {
+				t.Fatalf("count = %v, want %v", result, test.want)
+			}
+			table := "people"
+			tb, _ := db.LoadTable(&table)
+			plan, err := tb.planWhere(&test.where, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.where.Operator == "in" && plan.Estimate < test.want {
+				t.Fatalf("estimate = %v, below %v", plan.Estimate, test.want)
+			}
+		})
+	}
+}
+
+// newProductTable is the table the where operator tests share
+func newProductTable(t *testing.T) *Database {
+	return newTestTable(t, "products",
+		`{"id":1,"name":"Apple","price":50,"stock":0,"status":"new"}`,
+		`{"id":2,"name":"apricot","price":100,"stock":5,"status":"sold"}`,
+		`{"id":3,"name":"Banana","price":150,"stock":2,"status":"new"}`,
+		`{"id":4,"name":"cherry","price":100,"stock":1}`,
+	)
+}
+
+type whereTest struct {
+	name      string
+	where     []Where
+	whereType string
+	want      []float64 // nil when the query should fail
+}
+
+func runWhereTests(t *testing.T, db *Database, table string, tests []whereTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := db.Run(&Query{Table: table, Type: "mget", Where: test.where, WhereType: test.whereType})
+			if test.want == nil {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := docIds(t, result); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ids = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWhereRange(t *testing.T) {
+	runWhereTests(t, newProductTable(t), "products", []whereTest{
+		{"equal", []Where{{Field: "price", Value: 100.0}}, "", []float64{2, 4}},
+		{"greater", []Where{{Field: "price", Operator: ">", Value: 100.0}}, "", []float64{3}},
+		{"greater or equal", []Where{{Field: "price", Operator: ">=", Value: 100.0}}, "", []float64{2, 3, 4}},
+		{"less", []Where{{Field: "price", Operator: "<", Value: 100.0}}, "", []float64{1}},
+		{"less or equal", []Where{{Field: "price", Operator: "<=", Value: 100.0}}, "", []float64{1, 2, 4}},
+		{"not equal", []Where{{Field: "price", Operator: "!=", Value: 100.0}}, "", []float64{1, 3}},
+		{"below all", []Where{{Field: "price", Operator: "<", Value: 10.0}}, "", []float64{}},
+		{"and", []Where{{Field: "price", Operator: "<", Value: 150.0}, {Field: "stock", Operator: ">", Value: 0.0}}, "and", []float64{2, 4}},
+		{"or", []Where{{Field: "price", Operator: ">", Value: 100.0}, {Field: "stock", Value: 0.0}}, "or", []float64{1, 3}},
+		{"string range", []Where{{Field: "name", Operator: ">=", Value: "B"}}, "", []float64{3, 4}},
+		{"unknown operator", []Where{{Field: "price", Operator: "~", Value: 50.0}}, "", nil},
+		{"empty value", []Where{{Field: "price", Operator: ">"}}, "", nil},
+	})
+}
+
+func TestWhereIn(t *testing.T) {
+	runWhereTests(t, newProductTable(t), "products", []whereTest{
+		{"in", []Where{{Field: "status", Operator: "in", Value: []interface{}{"sold", "lost"}}}, "", []float64{2}},
+		{"in numbers", []Where{{Field: "price", Operator: "in", Value: []interface{}{50.0, 150.0}}}, "", []float64{1, 3}},
+		{"in nothing", []Where{{Field: "price", Operator: "in", Value: []interface{}{}}}, "", []float64{}},
+		{"nin skips docs without the field", []Where{{Field: "status", Operator: "nin", Value: []interface{}{"sold"}}}, "", []float64{1, 3}},
+		{"in without list", []Where{{Field: "price", Operator: "in", Value: 50.0}}, "", nil},
+		{"nin without list", []Where{{Field: "price", Operator: "nin", Value: "sold"}}, "", nil},
+	})
+}
+
+func TestWhereMatch(t *testing.T) {
+	runWhereTests(t, newProductTable(t), "products", []whereTest{
+		{"prefix ignores case", []Where{{Field: "name", Operator: "prefix", Value: "AP"}}, "", []float64{1, 2}},
+		{"contains", []Where{{Field: "name", Operator: "contains", Value: "an"}}, "", []float64{3}},
+		{"regex ignores case", []Where{{Field: "name", Operator: "regex", Value: "^[a-b]"}}, "", []float64{1, 2, 3}},
+		{"regex", []Where{{Field: "name", Operator: "regex", Value: "rr"}}, "", []float64{4}},
+		{"prefix without string", []Where{{Field: "price", Operator: "prefix", Value: 50.0}}, "", nil},
+		{"bad regex", []Where{{Field: "name", Operator: "regex", Value: "("}}, "", nil},
+	})
+}
+
+func TestWhereMatchCaseSensitive(t *testing.T) {
+	db := newTestTable(t, "people",
+		`{"id":1,"code":"AB"}`,
+		`{"id":2,"code":"ab"}`,
+	)
+	if err := db.SetCaseSensitive("people", "code", true); err != nil {
+		t.Fatal(err)
+	}
+	runWhereTests(t, db, "people", []whereTest{
+		{"prefix", []Where{{Field: "code", Operator: "prefix", Value: "A"}}, "", []float64{1}},
+		{"contains", []Where{{Field: "code", Operator: "contains", Value: "b"}}, "", []float64{2}},
+		{"regex", []Where{{Field: "code", Operator: "regex", Value: "^a"}}, "", []float64{2}},
+		{"regex ignore case", []Where{{Field: "code", Operator: "regex", Value: "^a", IgnoreCase: true}}, "", []float64{1, 2}},
+	})
+}